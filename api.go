@@ -3,12 +3,15 @@ package main
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/amitybell/srcvox/appstate"
 	"github.com/amitybell/srcvox/config"
 	"github.com/amitybell/srcvox/logs"
 	"github.com/amitybell/srcvox/sound"
 	"github.com/amitybell/srcvox/steam"
+	"github.com/amitybell/srcvox/translate"
+	"github.com/amitybell/srcvox/voicemod"
 )
 
 var (
@@ -36,7 +39,65 @@ func (a *API) UpdateConfig(c config.Config) error {
 }
 
 func (a *API) Sounds() []sound.SoundInfo {
-	return sound.SoundsList
+	return sound.Sounds()
+}
+
+func (a *API) Rules() sound.RulesInfo {
+	return sound.ActiveRulesInfo()
+}
+
+// Explain shows how text would be transformed by the pipeline if it were said by the user
+func (a *API) Explain(text string) (translate.Trace, error) {
+	state := a.app.State()
+	username := state.Presence.Username
+	return sound.Explain(state.Config, a.app.TextFilter(), a.app.TranslateVars(username), text)
+}
+
+// Voices returns the available voices
+func (a *API) Voices() []*sound.Voice {
+	return a.app.voiceList()
+}
+
+// PlayerVoice returns the name of the voice used for the player username
+func (a *API) PlayerVoice(username string) string {
+	return a.app.VoiceName(username)
+}
+
+// SetPlayerVoice sets the voice used for the player username
+// If voice is empty, the player's voice is reset.
+func (a *API) SetPlayerVoice(username, voice string) error {
+	return a.app.SetVoice(username, voice)
+}
+
+// PlayerTTSParams returns the synthesis params used for the player username
+func (a *API) PlayerTTSParams(username string) config.TTSParams {
+	return a.app.TTS(username).Params
+}
+
+// SynthesizeURL returns the URL of text spoken with voice and params p, to preview them in the settings
+//
+// If voice is empty, the user's voice is used; unset params use the user's settings.
+func (a *API) SynthesizeURL(text, voice string, p config.TTSParams) (string, error) {
+	u, ok := a.app.serverURL("/app.synthesize", ttsQuery(text, voice, p))
+	if !ok {
+		return "", ErrServerNotStarted
+	}
+	return u, nil
+}
+
+// Mutes returns the players that were muted by the spam detector
+func (a *API) Mutes() []voicemod.Mute {
+	return voicemod.Mutes(a.app.DB)
+}
+
+// TTSMetrics returns the metrics of the TTS worker pool
+func (a *API) TTSMetrics() voicemod.TTSMetrics {
+	return voicemod.Metrics()
+}
+
+// Unmute unmutes the player key, as returned by Mutes
+func (a *API) Unmute(key string) error {
+	return voicemod.Unmute(a.app.DB, key)
 }
 
 func (a *API) Games() []steam.GameInfo {
@@ -72,9 +133,8 @@ func (a *API) Presence() appstate.Presence {
 	return a.State().Presence
 }
 
-func (a *API) Servers(gameID steam.ID) (map[string]steam.Region, error) {
-	state := a.app.State()
-	return steam.QueryServerList(a.app.DB, state.ServerListMaxAge.D, gameID)
+func (a *API) Servers(gameID steam.ID, filter steam.Filter) (map[string]steam.Region, error) {
+	return a.app.serverList(gameID, filter)
 }
 
 func (a *API) ServerInfo(region steam.Region, addr string) (steam.ServerInfo, error) {
@@ -90,6 +150,84 @@ func (a *API) ServerInfo(region steam.Region, addr string) (steam.ServerInfo, er
 	return inf, err
 }
 
+// ScanServers queries the info of servers in the background, see App.ScanServers
+func (a *API) ScanServers(servers map[string]steam.Region) {
+	a.app.ScanServers(servers)
+}
+
+func (a *API) ServerPlayers(addr string) ([]steam.ServerPlayer, error) {
+	state := a.app.State()
+	return steam.QueryPlayers(a.app.DB, state.ServerInfoMaxAge.D, addr)
+}
+
+func (a *API) ServerRules(addr string) (steam.ServerRules, error) {
+	state := a.app.State()
+	return steam.QueryRules(a.app.DB, state.ServerInfoMaxAge.D, addr)
+}
+
+// Favourites returns the user's favourite servers
+func (a *API) Favourites() ([]steam.Favourite, error) {
+	return a.app.servers.Favourites()
+}
+
+// AddFavourite adds the server at addr to the favourites
+func (a *API) AddFavourite(addr, name string, gameID steam.ID) error {
+	return a.app.servers.AddFavourite(addr, name, gameID)
+}
+
+// RemoveFavourite removes the server at addr from the favourites
+func (a *API) RemoveFavourite(addr string) error {
+	return a.app.servers.RemoveFavourite(addr)
+}
+
+// SetFavouriteTags sets the tags of the favourite server at addr
+func (a *API) SetFavouriteTags(addr string, tags []string) error {
+	return a.app.servers.SetFavouriteTags(addr, tags)
+}
+
+// SetFavouriteNote sets the note of the favourite server at addr
+func (a *API) SetFavouriteNote(addr, note string) error {
+	return a.app.servers.SetFavouriteNote(addr, note)
+}
+
+// ServerHistory returns the servers the user joined, most recent first
+func (a *API) ServerHistory() ([]steam.HistoryEntry, error) {
+	return a.app.servers.History()
+}
+
+// ServerPopulation returns the population history of the server at addr over the last window
+//
+// window is a duration string e.g. `24h`, like the config durations.
+func (a *API) ServerPopulation(addr string, window string) (steam.PopHistory, error) {
+	var d config.Dur
+	if err := d.UnmarshalText([]byte(window)); err != nil {
+		return steam.PopHistory{}, fmt.Errorf("ServerPopulation: %w", err)
+	}
+	return steam.PopulationHistory(a.app.DB, addr, time.Now().Add(-d.D))
+}
+
+// WatchRules returns the rules that alert when servers fill up, change map or have known players on them
+func (a *API) WatchRules() ([]steam.WatchRule, error) {
+	return a.app.watcher.Rules()
+}
+
+// SetWatchRule adds or replaces a watch rule, returning it with its ID
+func (a *API) SetWatchRule(r steam.WatchRule) (steam.WatchRule, error) {
+	return a.app.watcher.SetRule(r)
+}
+
+// RemoveWatchRule removes the watch rule with ID id
+func (a *API) RemoveWatchRule(id string) error {
+	return a.app.watcher.RemoveRule(id)
+}
+
+// ImportSteamServers imports the favourites and history from Steam's server browser
+//
+// It returns the number of favourites and history entries that were added.
+func (a *API) ImportSteamServers() (favourites int, history int) {
+	return a.app.importServerBrowserHist()
+}
+
 func (a *API) AppAddr() (string, error) {
 	lsn := a.app.listener
 	if lsn == nil {
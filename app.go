@@ -11,22 +11,22 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/amitybell/memio"
-	"github.com/amitybell/piper"
-	asset "github.com/amitybell/piper-asset"
-	alan "github.com/amitybell/piper-voice-alan"
-	jenny "github.com/amitybell/piper-voice-jenny"
 	"github.com/amitybell/srcvox/appstate"
+	"github.com/amitybell/srcvox/audio"
 	"github.com/amitybell/srcvox/config"
 	"github.com/amitybell/srcvox/data"
 	"github.com/amitybell/srcvox/demo"
 	"github.com/amitybell/srcvox/errs"
 	"github.com/amitybell/srcvox/files"
 	"github.com/amitybell/srcvox/logs"
+	"github.com/amitybell/srcvox/rng"
 	"github.com/amitybell/srcvox/sound"
 	"github.com/amitybell/srcvox/steam"
 	"github.com/amitybell/srcvox/store"
@@ -69,8 +69,7 @@ type App struct {
 
 	listener net.Listener
 
-	ctx  context.Context
-	ttsl []*piper.TTS
+	ctx context.Context
 
 	wapp *application.Application
 
@@ -83,11 +82,36 @@ type App struct {
 
 	tmr struct {
 		reloadConfig *time.Timer
+		reloadRules  *time.Timer
+		reloadVoices *time.Timer
 	}
 
-	mu       sync.Mutex
-	limiters map[string]*rate.Limiter
-	ttsm     map[string]*piper.TTS
+	vm struct {
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+
+	scan struct {
+		mu     sync.Mutex
+		cancel context.CancelFunc
+	}
+
+	// filter is the compiled filter of the last policy used by TextFilter
+	filter struct {
+		mu     sync.Mutex
+		policy config.FilterPolicy
+		f      *translate.Filter
+		ok     bool
+	}
+
+	servers *steam.ServerBook
+	watcher *steam.Watcher
+
+	mu             sync.Mutex
+	limiters       map[string]*rate.Limiter
+	voices         []*sound.Voice
+	embeddedVoices []*sound.Voice
+	piperDir       string
 }
 
 func newStoppedTimer() *time.Timer {
@@ -99,10 +123,11 @@ func newStoppedTimer() *time.Timer {
 func NewApp(paths *config.Paths) *App {
 	app := &App{
 		Paths:    paths,
-		ttsm:     map[string]*piper.TTS{},
 		limiters: map[string]*rate.Limiter{},
 	}
 	app.tmr.reloadConfig = newStoppedTimer()
+	app.tmr.reloadRules = newStoppedTimer()
+	app.tmr.reloadVoices = newStoppedTimer()
 	app.API = &API{app: app}
 
 	app.state.p.Store(&appstate.AppState{})
@@ -170,19 +195,6 @@ func (app *App) Close() error {
 	return nil
 }
 
-func (app *App) TTS(key string) *piper.TTS {
-	app.mu.Lock()
-	defer app.mu.Unlock()
-
-	if tts, ok := app.ttsm[key]; ok {
-		return tts
-	}
-
-	tts := app.ttsl[len(app.ttsm)%len(app.ttsl)]
-	app.ttsm[key] = tts
-	return tts
-}
-
 func (app *App) screenSize(ctx context.Context) (int, int) {
 	screens, _ := runtime.ScreenGetAll(ctx)
 	for _, s := range screens {
@@ -194,6 +206,7 @@ func (app *App) screenSize(ctx context.Context) (int, int) {
 }
 
 func (app *App) onShutdown(ctx context.Context) {
+	app.stopVoiceMod()
 }
 
 func (app *App) onBeforeClose(ctx context.Context) bool {
@@ -264,11 +277,16 @@ func (app *App) Limiter(name string) *rate.Limiter {
 
 func (app *App) initWatch() {
 	watch.Path(app.Paths.ConfigDir)
+	watch.Path(app.Paths.VoicesDir)
 
 	watch.Notify(func(ev watch.Event) {
 		switch {
 		case ev.Name == app.Paths.ConfigFn:
 			app.tmr.reloadConfig.Reset(2 * time.Second)
+		case ev.Name == app.Paths.RulesFn:
+			app.tmr.reloadRules.Reset(2 * time.Second)
+		case filepath.Dir(ev.Name) == app.Paths.VoicesDir && isVoiceFile(ev.Name):
+			app.tmr.reloadVoices.Reset(2 * time.Second)
 		case filepath.Base(ev.Name) == "loginusers.vdf":
 			app.initPresence()
 		}
@@ -293,6 +311,9 @@ func (app *App) reloadConfig() {
 			Logs.Println("reloadConfig: ok")
 			return s
 		})
+		app.checkConfig(cfg)
+		// Config.TTSVoices might have changed
+		app.tmr.reloadVoices.Reset(0)
 	}
 }
 
@@ -304,22 +325,41 @@ func (app *App) initConfig() (config.Config, error) {
 		return cfg, err
 	}
 	app.Update(appstate.AppState{Config: cfg})
+	app.checkConfig(cfg)
 	return cfg, nil
 }
 
-func (app *App) initPiper(firstVoice string) error {
-	voices := []asset.Asset{jenny.Asset, alan.Asset}
-	if firstVoice == "alan" {
-		voices = []asset.Asset{alan.Asset, jenny.Asset}
+// checkConfig reports the problems in cfg that don't stop it from being used
+func (app *App) checkConfig(cfg config.Config) {
+	if err := translate.CheckFilterConfig(cfg.Filter); err != nil {
+		Logs.Println("checkConfig:", err)
+		app.Error(false, fmt.Errorf("Invalid filter rules are ignored: %w", err))
 	}
-	for _, asset := range voices {
-		tts, err := piper.New("", asset)
-		if err != nil {
-			return err
-		}
-		app.ttsl = append(app.ttsl, tts)
+}
+
+func (app *App) loadRules() {
+	rules, err := translate.LoadRules(app.Paths.RulesFn)
+	if err != nil {
+		Logs.Println("loadRules:", err)
+		app.Error(false, fmt.Errorf("Cannot load rules: %w", err))
+		return
 	}
-	return nil
+	for _, iss := range sound.CheckRules(rules) {
+		Logs.Debug("loadRules: "+iss.Message, slog.String("key", iss.Key), slog.String("target", iss.Target))
+	}
+	Logs.Println("loadRules: ok")
+}
+
+func (app *App) reloadRules() {
+	for range app.tmr.reloadRules.C {
+		app.loadRules()
+	}
+}
+
+func (app *App) initRules() {
+	go app.reloadRules()
+
+	app.loadRules()
 }
 
 func (app *App) initPresence() {
@@ -369,6 +409,9 @@ func (app *App) onStartup(ctx context.Context) {
 	}
 
 	app.initPresence()
+	app.initRules()
+	app.initServerBook()
+	app.startPopulationSampler()
 
 	if err := app.initPiper(cfg.FirstVoice); err != nil {
 		app.FatalError(err)
@@ -378,7 +421,28 @@ func (app *App) onStartup(ctx context.Context) {
 	app.initWatch()
 	app.initServer()
 
-	go voicemod.Run(ctx, app)
+	app.startVoiceMod(ctx)
+}
+
+func (app *App) startVoiceMod(ctx context.Context) {
+	ctx, app.vm.cancel = context.WithCancel(ctx)
+	app.vm.done = make(chan struct{})
+	go func() {
+		defer close(app.vm.done)
+		voicemod.Run(ctx, app)
+	}()
+}
+
+// stopVoiceMod stops voicemod, giving it a chance to restore the user's binds
+func (app *App) stopVoiceMod() {
+	if app.vm.cancel == nil {
+		return
+	}
+	app.vm.cancel()
+	select {
+	case <-app.vm.done:
+	case <-time.After(2 * time.Second):
+	}
 }
 
 func (app *App) DedicatedGameDir() string {
@@ -493,11 +557,46 @@ func (app *App) serveBgVideo(w http.ResponseWriter, r *http.Request) {
 func (app *App) serveSound(w http.ResponseWriter, r *http.Request) {
 	state := app.State()
 	pr := state.Presence
-	au, err := sound.SoundOrTTS(app.TTS(pr.Username), state.Config, pr.Username, r.URL.Query().Get("text"))
+	au, err := sound.SoundOrTTS(r.Context(), app.TTS(pr.Username), state.Config, app.TextFilter(), app.TranslateVars(pr.Username), r.URL.Query().Get("text"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	app.serveAudio(w, r, au)
+}
+
+// serveSynthesize previews text spoken by the user, with the voice and params in the query
+//
+// Params that aren't in the query use the user's settings.
+func (app *App) serveSynthesize(w http.ResponseWriter, r *http.Request) {
+	state := app.State()
+	pr := state.Presence
+	q := r.URL.Query()
+	tts := app.TTS(pr.Username)
+	if nm := q.Get("voice"); nm != "" {
+		v, ok := app.findVoice(nm)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown voice `%s`", nm), http.StatusBadRequest)
+			return
+		}
+		tts.Voice, tts.Params = v, app.TTSParams(pr.Username, v)
+	}
+	qp, err := parseTTSParams(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tts.Params = tts.Params.Over(qp)
+	au, err := sound.SoundOrTTS(r.Context(), tts, state.Config, app.TextFilter(), app.TranslateVars(pr.Username), q.Get("text"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	app.serveAudio(w, r, au)
+}
+
+func (app *App) serveAudio(w http.ResponseWriter, r *http.Request, au *audio.Audio) {
+	state := app.State()
 	f := memio.NewFile(nil)
 	if _, err := au.Encode(state.AudioDelay.D, state.AudioLimit.D, f, voicemod.DefaultVoiceFormat); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -510,7 +609,7 @@ func (app *App) initServeMux() {
 	app.serveMux.HandleFunc("/app.bgvideo", app.serveBgVideo)
 	app.serveMux.HandleFunc("/app.mapimage", app.serveMapImage)
 	app.serveMux.HandleFunc("/app.sound", app.serveSound)
-	app.serveMux.HandleFunc("/app.synthesize", app.serveSound)
+	app.serveMux.HandleFunc("/app.synthesize", app.serveSynthesize)
 	app.serveMux.HandleFunc("/app.avatar", app.serveAvatar)
 	app.serveMux.HandleFunc("/favicon.ico", app.serveFavicon)
 }
@@ -602,7 +701,8 @@ func (app *App) UpdateConfig(cfg config.Config) error {
 	done := make(chan error)
 	app.UpdateState(func(s appstate.AppState) appstate.AppState {
 		var err error
-		cfg, changed := s.Config.Merge(cfg)
+		var changed bool
+		cfg, changed = s.Config.Merge(cfg)
 		if changed {
 			err = config.Write(app.Paths.ConfigFn, cfg)
 		}
@@ -610,7 +710,11 @@ func (app *App) UpdateConfig(cfg config.Config) error {
 		done <- err
 		return s
 	})
-	return <-done
+	if err := <-done; err != nil {
+		return err
+	}
+	app.checkConfig(cfg)
+	return nil
 }
 
 func (app *App) Logs() *logs.Logger {
@@ -658,6 +762,83 @@ func (app *App) VoiceModNetcon() (voicemod.Conn, error) {
 	return c, nil
 }
 
+func (app *App) TranslateVars(username string) translate.Vars {
+	state := app.State()
+	pr := state.Presence
+	vars := translate.Vars{
+		"name":          app.SpokenName(username),
+		"username":      username,
+		"map":           "",
+		"server":        "",
+		"players":       "",
+		"random_player": "",
+		"time":          time.Now().Format("3:04 PM"),
+		"game":          "",
+	}
+	if g, ok := steam.GamesMap[pr.GameID]; ok {
+		vars["game"] = g.Title
+	}
+	if inf, ok := steam.CachedServerInfo(app.DB, pr.Server); ok && pr.Server != "" {
+		vars["map"] = inf.Map
+		vars["server"] = inf.Name
+		vars["players"] = strconv.Itoa(inf.Players)
+	}
+	if hums := pr.Humans.Slice(); len(hums) != 0 {
+		vars["players"] = strconv.Itoa(len(hums))
+		vars["random_player"] = app.SpokenName(rng.Elem(hums).Username)
+	}
+	return vars
+}
+
+// TextFilter returns the filter for the current game and server
+//
+// The filter is only recompiled when its policy changes e.g. after a config change, or joining another server.
+func (app *App) TextFilter() *translate.Filter {
+	state := app.State()
+	pr := state.Presence
+	policy := state.Filter.Policy(pr.GameID.String(), pr.Server)
+
+	app.filter.mu.Lock()
+	defer app.filter.mu.Unlock()
+
+	if app.filter.ok && reflect.DeepEqual(app.filter.policy, policy) {
+		return app.filter.f
+	}
+	// invalid rules are reported by checkConfig, and the filter of the rest is still used
+	f, _ := translate.NewFilter(policy)
+	app.filter.policy, app.filter.f, app.filter.ok = policy, f, true
+	return f
+}
+
+// ScanServers queries the info of servers in the background, cancelling the previous scan
+//
+// Each ServerInfo is emitted as appstate.SvServerInfoChange as it arrives.
+func (app *App) ScanServers(servers map[string]steam.Region) {
+	ctx, cancel := context.WithCancel(app.ctx)
+	app.scan.mu.Lock()
+	if app.scan.cancel != nil {
+		app.scan.cancel()
+	}
+	app.scan.cancel = cancel
+	app.scan.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		sc := &steam.Scanner{
+			DB:      app.DB,
+			MaxAge:  app.State().ServerInfoMaxAge.D,
+			Options: steam.DefaultScanOptions,
+		}
+		err := sc.Scan(ctx, servers, func(inf steam.ServerInfo) {
+			app.Emit(appstate.SvServerInfoChange, inf)
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			Logs.Println("ScanServers:", err)
+		}
+	}()
+}
+
 func (app *App) VoiceModPresence(ts time.Time, server string, hums, bots data.SliceSet[steam.Profile]) {
 	app.UpdateState(func(s appstate.AppState) appstate.AppState {
 		if s.Presence.Server == server &&
@@ -665,6 +846,12 @@ func (app *App) VoiceModPresence(ts time.Time, server string, hums, bots data.Sl
 			s.Presence.Humans.Equal(hums) {
 			return s
 		}
+		if s.Presence.Server != server && server != "" {
+			go app.joinServer(server, s.Presence.GameID, ts)
+		}
+		if !s.Presence.Humans.Equal(hums) {
+			go app.seePlayers(hums.Slice(), ts)
+		}
 		s.Presence.Bots = bots
 		s.Presence.Humans = hums
 		s.Presence.Server = server
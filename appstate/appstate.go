@@ -11,6 +11,8 @@ const (
 	SvConfigChangeEvent   = "sv.ConfigChange"
 	SvErrorChangeEvent    = "sv.ErrorChange"
 	SvServerInfoChange    = "sv.ServerInfoChange"
+	SvWatchAlert          = "sv.WatchAlert"
+	SvNotification        = "sv.Notification"
 )
 
 type Reducer func(p AppState) AppState
@@ -24,3 +24,16 @@ type Presence struct {
 	Server      string                       `json:"server"`
 	Ts          time.Time                    `json:"ts"`
 }
+
+// PlayerID returns the steam ID of the player username on the current server, if known
+func (p Presence) PlayerID(username string) steam.ID {
+	if username == p.Username {
+		return p.UserID
+	}
+	for _, h := range p.Humans.Slice() {
+		if h.Username == username {
+			return h.UserID
+		}
+	}
+	return 0
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/amitybell/srcvox/errs"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
 	"github.com/gopxl/beep/flac"
+	"github.com/gopxl/beep/generators"
 	"github.com/gopxl/beep/mp3"
 	"github.com/gopxl/beep/vorbis"
 	"github.com/gopxl/beep/wav"
@@ -120,3 +122,66 @@ func Read(name string, src *memio.File) (*Audio, error) {
 	}
 	return a, nil
 }
+
+// Tone returns a sine tone of frequency freq, lasting for dur
+func Tone(name string, freq float64, dur time.Duration, format beep.Format) (*Audio, error) {
+	tone, err := generators.SineTone(format.SampleRate, freq)
+	if err != nil {
+		return nil, fmt.Errorf("Tone: %w", err)
+	}
+	buf := beep.NewBuffer(format)
+	buf.Append(beep.Take(format.SampleRate.N(dur), &effects.Volume{Streamer: tone, Base: 2, Volume: -2}))
+	return fromBuffer(name, buf), nil
+}
+
+// Volume returns au with its volume changed by vol, where each step of 1 doubles or halves the amplitude
+func Volume(au *Audio, vol float64) (*Audio, error) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	if err := au.Stream.Seek(0); err != nil {
+		return nil, fmt.Errorf("Volume: %w", err)
+	}
+	buf := beep.NewBuffer(au.Format)
+	buf.Append(&effects.Volume{Streamer: au.Stream, Base: 2, Volume: vol})
+	v := fromBuffer(au.Name, buf)
+	v.TTS = au.TTS
+	return v, nil
+}
+
+// Concat returns the audio of each part played in sequence, converted to format
+func Concat(name string, format beep.Format, parts ...*Audio) (*Audio, error) {
+	buf := beep.NewBuffer(format)
+	for _, au := range parts {
+		if err := au.appendTo(buf); err != nil {
+			return nil, fmt.Errorf("Concat: %s: %w", au.Name, err)
+		}
+	}
+	return fromBuffer(name, buf), nil
+}
+
+func (au *Audio) appendTo(buf *beep.Buffer) error {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	if err := au.Stream.Seek(0); err != nil {
+		return err
+	}
+	var stream beep.Streamer = au.Stream
+	if sr := buf.Format().SampleRate; au.Format.SampleRate != sr {
+		stream = beep.Resample(DefaultResampleQuality, au.Format.SampleRate, sr, stream)
+	}
+	buf.Append(stream)
+	return nil
+}
+
+func fromBuffer(name string, buf *beep.Buffer) *Audio {
+	format := buf.Format()
+	return &Audio{
+		Name:   name,
+		Stream: buf.Streamer(0, buf.Len()),
+		Format: format,
+		Size:   buf.Len(),
+		Dur:    format.SampleRate.D(buf.Len()),
+	}
+}
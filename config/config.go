@@ -27,6 +27,7 @@ var DefaultConfig = func() Config {
 		RateLimit:        Dur{D: 5 * time.Second},
 		ServerListMaxAge: Dur{1 * time.Hour},
 		ServerInfoMaxAge: Dur{1 * time.Minute},
+		Spam:             DefaultSpamConfig,
 	}
 	cfg, _ = def.Merge(cfg)
 	return cfg
@@ -41,7 +42,7 @@ func mergeVal[T comparable](p *T, v T) bool {
 	return false
 }
 
-func mergePositive[T ~int](p *T, v T) bool {
+func mergePositive[T ~int | ~float64](p *T, v T) bool {
 	if v > 0 {
 		*p = v
 		return true
@@ -73,6 +74,14 @@ func mergeMap[T map[K]V, K comparable, V any](p *T, v T) bool {
 	return false
 }
 
+func mergeSlice[T []V, V any](p *T, v T) bool {
+	if v != nil {
+		*p = v
+		return true
+	}
+	return false
+}
+
 type ConnInfo struct {
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
@@ -95,20 +104,30 @@ func (c ConnInfo) Merge(p ConnInfo) (ci ConnInfo, changed bool) {
 }
 
 type Config struct {
-	Netcon           ConnInfo        `json:"netcon"`
-	Rcon             ConnInfo        `json:"rcon"`
-	AudioDelay       Dur             `json:"audioDelay"`
-	AudioLimit       Dur             `json:"audioLimit"`
-	AudioLimitTTS    Dur             `json:"audioLimitTTS"`
-	TextLimit        int             `json:"textLimit"`
-	IncludeUsernames map[string]bool `json:"includeUsernames"`
-	ExcludeUsernames map[string]bool `json:"excludeUsernames"`
-	Hosts            map[string]bool `json:"hosts"`
-	FirstVoice       string          `json:"firstVoice"`
-	LogLevel         string          `json:"logLevel"`
-	RateLimit        Dur             `json:"rateLimit"`
-	ServerListMaxAge Dur             `json:"serverListMaxAge"`
-	ServerInfoMaxAge Dur             `json:"serverInfoMaxAge"`
+	Netcon           ConnInfo             `json:"netcon"`
+	Rcon             ConnInfo             `json:"rcon"`
+	AudioDelay       Dur                  `json:"audioDelay"`
+	AudioLimit       Dur                  `json:"audioLimit"`
+	AudioLimitTTS    Dur                  `json:"audioLimitTTS"`
+	TextLimit        int                  `json:"textLimit"`
+	IncludeUsernames map[string]bool      `json:"includeUsernames"`
+	ExcludeUsernames map[string]bool      `json:"excludeUsernames"`
+	Hosts            map[string]bool      `json:"hosts"`
+	Binds            map[string]string    `json:"binds"`
+	Pronounce        map[string]string    `json:"pronounce"`
+	Voices           map[string]string    `json:"voices"`
+	VoiceParams      map[string]TTSParams `json:"voiceParams"`
+	TTSVoices        map[string]TTSVoice  `json:"ttsVoices"`
+	PlayerParams     map[string]TTSParams `json:"playerParams"`
+	Filter           FilterConfig         `json:"filter"`
+	Normalize        NormalizeConfig      `json:"normalize"`
+	Pipeline         []string             `json:"pipeline"`
+	Spam             SpamConfig           `json:"spam"`
+	FirstVoice       string               `json:"firstVoice"`
+	LogLevel         string               `json:"logLevel"`
+	RateLimit        Dur                  `json:"rateLimit"`
+	ServerListMaxAge Dur                  `json:"serverListMaxAge"`
+	ServerInfoMaxAge Dur                  `json:"serverInfoMaxAge"`
 
 	Minimized *bool `json:"minimized"`
 	Demo      *bool `json:"demo"`
@@ -132,6 +151,16 @@ func (c Config) Merge(p Config) (cfg Config, changed bool) {
 	changed = mergeMap(&c.IncludeUsernames, p.IncludeUsernames) || changed
 	changed = mergeMap(&c.ExcludeUsernames, p.ExcludeUsernames) || changed
 	changed = mergeMap(&c.Hosts, p.Hosts) || changed
+	changed = mergeMap(&c.Binds, p.Binds) || changed
+	changed = mergeMap(&c.Pronounce, p.Pronounce) || changed
+	changed = mergeMap(&c.Voices, p.Voices) || changed
+	changed = mergeMap(&c.VoiceParams, p.VoiceParams) || changed
+	changed = mergeMap(&c.TTSVoices, p.TTSVoices) || changed
+	changed = mergeMap(&c.PlayerParams, p.PlayerParams) || changed
+	changed = mergeObj(&c.Filter, p.Filter) || changed
+	changed = mergeObj(&c.Normalize, p.Normalize) || changed
+	changed = mergeSlice(&c.Pipeline, p.Pipeline) || changed
+	changed = mergeObj(&c.Spam, p.Spam) || changed
 	changed = mergeVal(&c.LogLevel, p.LogLevel) || changed
 	changed = mergeDur(&c.RateLimit, p.RateLimit) || changed
 	changed = mergeDur(&c.ServerListMaxAge, p.ServerListMaxAge) || changed
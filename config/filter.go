@@ -0,0 +1,89 @@
+package config
+
+const (
+	// FilterWord matches whole words or phrases
+	FilterWord = "word"
+	// FilterSubstring matches anywhere inside a word
+	FilterSubstring = "substring"
+	// FilterRegex matches a regular expression against the normalized message
+	FilterRegex = "regex"
+
+	// FilterDrop drops the whole message
+	FilterDrop = "drop"
+	// FilterMask removes the matched words from the message
+	FilterMask = "mask"
+	// FilterBleep replaces the matched words with a bleep
+	FilterBleep = "bleep"
+)
+
+type FilterRule struct {
+	Pattern string `json:"pattern"`
+	// Match is one of FilterWord (the default), FilterSubstring or FilterRegex
+	Match string `json:"match"`
+	// Action is one of FilterDrop, FilterMask or FilterBleep
+	// If empty, the policy's action is used.
+	Action string `json:"action"`
+}
+
+type FilterPolicy struct {
+	Enabled *bool `json:"enabled"`
+	// Action is the default action for rules that don't specify one
+	Action string       `json:"action"`
+	Rules  []FilterRule `json:"rules"`
+}
+
+func (p FilterPolicy) IsEnabled() bool {
+	return Enabled(p.Enabled)
+}
+
+// Merge returns p overridden by q
+//
+// The rules in q are added to the rules in p.
+func (p FilterPolicy) Merge(q FilterPolicy) (FilterPolicy, bool) {
+	changed := mergeVal(&p.Enabled, q.Enabled)
+	changed = mergeVal(&p.Action, q.Action) || changed
+	if len(q.Rules) != 0 {
+		p.Rules = append(p.Rules[:len(p.Rules):len(p.Rules)], q.Rules...)
+		changed = true
+	}
+	return p, changed
+}
+
+type FilterConfig struct {
+	FilterPolicy
+
+	// Games are the policies for specific games, keyed by game ID
+	Games map[string]FilterPolicy `json:"games"`
+
+	// Servers are the policies for specific servers, keyed by address
+	Servers map[string]FilterPolicy `json:"servers"`
+}
+
+// Policy returns the policy for the server addr running the game gameID
+//
+// The server policy takes precedence over the game policy, which takes precedence over the default policy.
+func (c FilterConfig) Policy(gameID, addr string) FilterPolicy {
+	p := c.FilterPolicy
+	if p.Action == "" {
+		p.Action = FilterMask
+	}
+	if g, ok := c.Games[gameID]; ok {
+		p, _ = p.Merge(g)
+	}
+	if s, ok := c.Servers[addr]; ok {
+		p, _ = p.Merge(s)
+	}
+	return p
+}
+
+func (c FilterConfig) Merge(p FilterConfig) (FilterConfig, bool) {
+	changed := mergeVal(&c.Enabled, p.Enabled)
+	changed = mergeVal(&c.Action, p.Action) || changed
+	if p.Rules != nil {
+		c.Rules = p.Rules
+		changed = true
+	}
+	changed = mergeMap(&c.Games, p.Games) || changed
+	changed = mergeMap(&c.Servers, p.Servers) || changed
+	return c, changed
+}
@@ -0,0 +1,31 @@
+package config
+
+// NormalizeConfig toggles the rules used to normalize chat messages before they're spoken
+//
+// All rules are enabled by default.
+type NormalizeConfig struct {
+	// Numbers expands numbers, ordinals and times to words
+	Numbers *bool `json:"numbers"`
+	// Links collapses URLs and IP:port addresses into "a link"
+	Links *bool `json:"links"`
+	// Emoji maps emoji to words, or removes them
+	Emoji *bool `json:"emoji"`
+	// Repeats collapses runs of repeated letters and punctuation
+	Repeats *bool `json:"repeats"`
+	// Caps lower-cases shouting, but keeps acronyms
+	Caps *bool `json:"caps"`
+}
+
+func (n NormalizeConfig) Merge(p NormalizeConfig) (NormalizeConfig, bool) {
+	changed := mergeVal(&n.Numbers, p.Numbers)
+	changed = mergeVal(&n.Links, p.Links) || changed
+	changed = mergeVal(&n.Emoji, p.Emoji) || changed
+	changed = mergeVal(&n.Repeats, p.Repeats) || changed
+	changed = mergeVal(&n.Caps, p.Caps) || changed
+	return n, changed
+}
+
+// Enabled reports whether the optional toggle p is enabled, defaulting to true
+func Enabled(p *bool) bool {
+	return p == nil || *p
+}
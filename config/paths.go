@@ -10,7 +10,9 @@ import (
 type Paths struct {
 	ConfigDir      string
 	ConfigFn       string
+	RulesFn        string
 	DataDir        string
+	VoicesDir      string
 	WebviewDataDir string
 	DBDir          string
 	LogsFn         string
@@ -36,7 +38,9 @@ func NewPaths(configDir, dataDir string) (*Paths, error) {
 	return &Paths{
 		ConfigDir:      configDir,
 		ConfigFn:       filepath.Join(configDir, "config.json"),
+		RulesFn:        filepath.Join(configDir, "rules.json"),
 		DataDir:        dataDir,
+		VoicesDir:      filepath.Join(dataDir, "voices"),
 		WebviewDataDir: filepath.Join(dataDir, "webview"),
 		DBDir:          filepath.Join(dataDir, "data.pb"),
 		LogsFn:         filepath.Join(dataDir, "logs.json"),
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"time"
+)
+
+var DefaultSpamConfig = SpamConfig{
+	Window:              Dur{D: 2 * time.Minute},
+	FloodWindow:         Dur{D: 10 * time.Second},
+	FloodMessages:       4,
+	GlobalFloodMessages: 12,
+	DuplicateWindow:     Dur{D: 1 * time.Minute},
+	Similarity:          0.8,
+	MaxLength:           160,
+	MaxCaps:             0.8,
+	Strikes:             3,
+	StrikeWindow:        Dur{D: 5 * time.Minute},
+	MuteDuration:        Dur{D: 10 * time.Minute},
+}
+
+// SpamConfig configures the chat spam detector
+//
+// Each message that's spam is ignored and counts as a strike against the player.
+// When a player gets Strikes strikes within StrikeWindow, they're muted for MuteDuration.
+type SpamConfig struct {
+	Enabled *bool `json:"enabled"`
+
+	// Window is how long messages are remembered
+	Window Dur `json:"window"`
+
+	// FloodMessages is the number of messages a player can send within FloodWindow
+	FloodWindow   Dur `json:"floodWindow"`
+	FloodMessages int `json:"floodMessages"`
+
+	// GlobalFloodMessages is the number of messages all players can send within FloodWindow
+	// Messages over the limit are ignored, but don't count as strikes.
+	GlobalFloodMessages int `json:"globalFloodMessages"`
+
+	// DuplicateWindow is how long a message is considered a duplicate of an earlier one
+	// Duplicates of another player's message are ignored, but don't count as strikes.
+	DuplicateWindow Dur `json:"duplicateWindow"`
+	// Similarity is how similar, from 0 to 1, messages must be to be considered duplicates
+	Similarity float64 `json:"similarity"`
+
+	// MaxLength is the maximum length of a message
+	MaxLength int `json:"maxLength"`
+	// MaxCaps is the maximum ratio, from 0 to 1, of letters that can be upper-case
+	MaxCaps float64 `json:"maxCaps"`
+
+	Strikes      int `json:"strikes"`
+	StrikeWindow Dur `json:"strikeWindow"`
+	MuteDuration Dur `json:"muteDuration"`
+}
+
+func (c SpamConfig) IsEnabled() bool {
+	return Enabled(c.Enabled)
+}
+
+func (c SpamConfig) Merge(p SpamConfig) (SpamConfig, bool) {
+	changed := mergeVal(&c.Enabled, p.Enabled)
+	changed = mergeDur(&c.Window, p.Window) || changed
+	changed = mergeDur(&c.FloodWindow, p.FloodWindow) || changed
+	changed = mergePositive(&c.FloodMessages, p.FloodMessages) || changed
+	changed = mergePositive(&c.GlobalFloodMessages, p.GlobalFloodMessages) || changed
+	changed = mergeDur(&c.DuplicateWindow, p.DuplicateWindow) || changed
+	changed = mergeVal(&c.Similarity, p.Similarity) || changed
+	changed = mergePositive(&c.MaxLength, p.MaxLength) || changed
+	changed = mergeVal(&c.MaxCaps, p.MaxCaps) || changed
+	changed = mergePositive(&c.Strikes, p.Strikes) || changed
+	changed = mergeDur(&c.StrikeWindow, p.StrikeWindow) || changed
+	changed = mergeDur(&c.MuteDuration, p.MuteDuration) || changed
+	return c, changed
+}
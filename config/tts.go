@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	PiperBackend  = "piper"
+	EspeakBackend = "espeak"
+	HTTPBackend   = "http"
+
+	MinTTSRate = 0.25
+	MaxTTSRate = 4
+
+	MaxTTSVariability = 2
+)
+
+// TTSParams are the synthesis parameters of a voice
+//
+// Zero values use the model's defaults.
+type TTSParams struct {
+	// Rate is the speaking rate; 2 is twice as fast. It's the inverse of piper's length_scale
+	Rate float64 `json:"rate"`
+	// Variability is the expressiveness of the pitch and intonation; piper's noise_scale
+	Variability float64 `json:"variability"`
+	// Cadence is the variability of phoneme durations; piper's noise_w
+	Cadence float64 `json:"cadence"`
+	// Speaker is the name or ID of the speaker in a multi-speaker model
+	Speaker string `json:"speaker"`
+}
+
+// Over returns p, with the fields that are set in q replaced
+func (p TTSParams) Over(q TTSParams) TTSParams {
+	mergePositive(&p.Rate, q.Rate)
+	mergePositive(&p.Variability, q.Variability)
+	mergePositive(&p.Cadence, q.Cadence)
+	mergeVal(&p.Speaker, q.Speaker)
+	return p
+}
+
+// Clamp returns p with its fields limited to the supported ranges
+func (p TTSParams) Clamp() TTSParams {
+	if p.Rate != 0 {
+		p.Rate = min(max(p.Rate, MinTTSRate), MaxTTSRate)
+	}
+	p.Variability = min(max(p.Variability, 0), MaxTTSVariability)
+	p.Cadence = min(max(p.Cadence, 0), MaxTTSVariability)
+	return p
+}
+
+// Key returns a string that uniquely identifies p, for use in cache keys
+func (p TTSParams) Key() string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	return fmt.Sprintf("r=%s,v=%s,c=%s,s=%q", f(p.Rate), f(p.Variability), f(p.Cadence), p.Speaker)
+}
+
+// TTSVoice configures a voice that's spoken by one of the TTS backends
+type TTSVoice struct {
+	// Backend is one of PiperBackend (the default), EspeakBackend or HTTPBackend
+	Backend string `json:"backend"`
+	// Model is the path of the .onnx model, for the piper backend
+	Model string `json:"model"`
+	// Voice is the espeak-ng voice, or the voice sent to the HTTP backend
+	Voice string `json:"voice"`
+	// URL is the endpoint of the HTTP backend
+	URL string `json:"url"`
+	// Exe is the path of the piper or espeak-ng executable
+	Exe      string `json:"exe"`
+	Language string `json:"language"`
+	// Timeout limits HTTP requests
+	Timeout Dur `json:"timeout"`
+}
@@ -22,4 +22,7 @@ var (
 
 	//go:embed default/avatar.jpg
 	DefaultAvatar []byte
+
+	//go:embed default/rules.json
+	DefaultRules []byte
 )
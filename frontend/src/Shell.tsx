@@ -15,6 +15,7 @@ import {
   TablerIconsProps,
 } from '@tabler/icons-react'
 import { ComponentType, ReactElement, useEffect, useState } from 'react'
+import { EventsOn } from '../wailsjs/runtime'
 import Credits from './Credits'
 import Error from './Error'
 import Presence, { PresenceAvatar } from './Presence'
@@ -23,7 +24,7 @@ import Settings from './Settings'
 import Snapshots from './Snapshots'
 import Soundboard from './Soundboard'
 import Wallpaper from './Wallpaper'
-import { GameInfo } from './appstate'
+import { GameInfo, ServerInfo } from './appstate'
 import { useAppError, useGames } from './hooks/query'
 
 type PageName =
@@ -89,6 +90,12 @@ function Side({ pages, active, goto }: SideProps) {
   )
 }
 
+interface WatchAlert {
+  rule: { id: string }
+  server: ServerInfo
+  message: string
+}
+
 interface ContentProps {
   page: Page
   games: GameInfo[]
@@ -110,6 +117,16 @@ function Content({ page, games, activeGameIdx, setActiveGameIdx }: ContentProps)
     }
   })
 
+  useEffect(() => {
+    return EventsOn('sv.Notification', (alert: WatchAlert) => {
+      notifications.show({
+        id: `sv.watch.${alert.rule.id}.${alert.server.addr}`,
+        title: alert.server.name || alert.server.addr,
+        message: alert.message,
+      })
+    })
+  }, [])
+
   if (err.type !== 'ok') {
     return err.alt
   }
@@ -36,6 +36,12 @@ export class ServerInfo implements Omit<steam.ServerInfo, 'convertValues'> {
   region: number
   country: string
   ts: Date
+  appID: number
+  version: string
+  tags: string[]
+  steamID: string
+  sourceTVPort: number
+  sourceTVName: string
 
   sortName: string
 
@@ -53,6 +59,12 @@ export class ServerInfo implements Omit<steam.ServerInfo, 'convertValues'> {
     this.region = coerce(0xff, p.region)
     this.country = coerce('', p.country)
     this.ts = new Date(coerce('', p.ts) || '0000')
+    this.appID = coerce(0, p.appID)
+    this.version = coerce('', p.version)
+    this.tags = coerce([], p.tags)
+    this.steamID = coerce('', p.steamID)
+    this.sourceTVPort = coerce(0, p.sourceTVPort)
+    this.sourceTVName = coerce('', p.sourceTVName)
     this.sortName =
       // remove prefixes [abc] | (abc) | \W+
       naturallySortable(
@@ -219,6 +231,16 @@ export class Config implements Required<Omit<config.Config, 'convertValues'>> {
   includeUsernames: { [key: string]: boolean }
   excludeUsernames: { [key: string]: boolean }
   hosts: { [key: string]: boolean }
+  binds: { [key: string]: string }
+  pronounce: { [key: string]: string }
+  voices: { [key: string]: string }
+  voiceParams: { [key: string]: config.TTSParams }
+  ttsVoices: { [key: string]: config.TTSVoice }
+  playerParams: { [key: string]: config.TTSParams }
+  filter: config.FilterConfig
+  normalize: config.NormalizeConfig
+  pipeline: string[]
+  spam: config.SpamConfig
   firstVoice: string
   logLevel: string
   rateLimit: Dur
@@ -239,6 +261,16 @@ export class Config implements Required<Omit<config.Config, 'convertValues'>> {
     this.includeUsernames = coerce({}, p.includeUsernames)
     this.excludeUsernames = coerce({}, p.excludeUsernames)
     this.hosts = coerce({}, p.hosts)
+    this.binds = coerce({}, p.binds)
+    this.pronounce = coerce({}, p.pronounce)
+    this.voices = coerce({}, p.voices)
+    this.voiceParams = coerce({}, p.voiceParams)
+    this.ttsVoices = coerce({}, p.ttsVoices)
+    this.playerParams = coerce({}, p.playerParams)
+    this.filter = new config.FilterConfig(p.filter)
+    this.normalize = new config.NormalizeConfig(p.normalize)
+    this.pipeline = coerce<string[]>([], p.pipeline)
+    this.spam = new config.SpamConfig(p.spam)
     this.firstVoice = coerce('', p.firstVoice)
     this.logLevel = coerce('', p.logLevel)
     this.rateLimit = coerce('', p.rateLimit)
@@ -7,6 +7,8 @@ import {
 } from '@tanstack/react-query'
 import deepEqual from 'deep-equal'
 import { ReactElement, useEffect, useId, useMemo, useRef, useState } from 'react'
+import { steam } from '../../wailsjs/go/models'
+import { EventsOn } from '../../wailsjs/runtime'
 import { app, useAppEvent } from '../api'
 import {
   AppError,
@@ -199,39 +201,57 @@ export function useServerInfos(
   refresh: number,
   less: (a: ServerInfo, b: ServerInfo) => boolean,
 ): ServerInfo[] {
-  const res = useQueries({
-    queries: Object.entries(addrs).map(([addr, reg]) => ({
-      queryKey: ['useServerInfos', addr],
-      queryFn: () => app.ServerInfo(reg, addr),
-      select: (p: Partial<ServerInfo>) => new ServerInfo(p),
-      refetchInterval: refresh > 0 ? refresh : undefined,
-    })),
-  })
+  const [infos, setInfos] = useState<Record<string, ServerInfo>>({})
+  const servers = useMemoEq(() => addrs, addrs)
+
+  useEffect(() => {
+    return EventsOn('sv.ServerInfoChange', (p: Partial<ServerInfo>) => {
+      const inf = new ServerInfo(p)
+      setInfos((m) => ({ ...m, [inf.addr]: inf }))
+    })
+  }, [])
+
+  // the servers are queried together by a single scan, instead of a query per server
+  useEffect(() => {
+    const scan = () => {
+      app.ScanServers(servers)
+    }
+    scan()
+    if (refresh <= 0) {
+      return
+    }
+    const id = setInterval(scan, refresh)
+    return () => clearInterval(id)
+  }, [servers, refresh])
 
+  // the info of the server we're on is updated with its status
   const pr = usePresence()
   useEffect(() => {
-    if (pr.type !== 'ok' || !pr.v.server) {
+    if (pr.type !== 'ok' || !pr.v.server || !(pr.v.server in servers)) {
       return
     }
-    res.find((p) => p.data?.addr === pr.v.server)?.refetch()
-  }, [res, pr])
+    app
+      .ServerInfo(servers[pr.v.server], pr.v.server)
+      .then((p) => {
+        const inf = new ServerInfo(p)
+        setInfos((m) => ({ ...m, [inf.addr]: inf }))
+      })
+      .catch(() => {})
+  }, [servers, pr])
 
-  return res
-    .map((p) => {
-      const r = qResult(p)
-      if (r.type === 'ok') {
-        return r.v
-      }
-      return null
-    })
-    .filter((p): p is ServerInfo => p != null)
+  return Object.values(infos)
+    .filter((p) => p.addr in servers)
     .sort((a, b) => (less(a, b) ? -1 : 1))
 }
 
-export function useServers(gameID: number, refresh: number): QResult<{ [key: string]: Region }> {
+export function useServers(
+  gameID: number,
+  refresh: number,
+  filter: steam.Filter = new steam.Filter(),
+): QResult<{ [key: string]: Region }> {
   return useData(
-    `app.Servers(${gameID})`,
-    () => app.Servers(gameID),
+    `app.Servers(${gameID}, ${JSON.stringify(filter)})`,
+    () => app.Servers(gameID, filter),
     (p) => coerce({}, p),
     {
       refetchInterval: refresh > 0 ? refresh : false,
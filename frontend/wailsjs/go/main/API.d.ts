@@ -1,33 +1,83 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {steam} from '../models';
 import {config} from '../models';
 import {appstate} from '../models';
-import {steam} from '../models';
+import {translate} from '../models';
 import {logs} from '../models';
+import {voicemod} from '../models';
 import {sound} from '../models';
 
+export function AddFavourite(arg1:string,arg2:string,arg3:steam.ID):Promise<void>;
+
 export function AppAddr():Promise<string>;
 
 export function Config():Promise<config.Config>;
 
 export function Error():Promise<appstate.AppError>;
 
+export function Explain(arg1:string):Promise<translate.Trace>;
+
+export function Favourites():Promise<Array<steam.Favourite>>;
+
 export function Games():Promise<Array<steam.GameInfo>>;
 
+export function ImportSteamServers():Promise<number|number>;
+
 export function LaunchOptions(arg1:steam.ID,arg2:steam.ID):Promise<string>;
 
 export function Log(arg1:logs.APILog):Promise<void>;
 
+export function Mutes():Promise<Array<voicemod.Mute>>;
+
+export function PlayerTTSParams(arg1:string):Promise<config.TTSParams>;
+
+export function PlayerVoice(arg1:string):Promise<string>;
+
 export function Presence():Promise<appstate.Presence>;
 
 export function Profile(arg1:steam.ID,arg2:string):Promise<steam.Profile>;
 
+export function RemoveFavourite(arg1:string):Promise<void>;
+
+export function RemoveWatchRule(arg1:string):Promise<void>;
+
+export function Rules():Promise<sound.RulesInfo>;
+
+export function ScanServers(arg1:{[key: string]: steam.Region}):Promise<void>;
+
+export function ServerHistory():Promise<Array<steam.HistoryEntry>>;
+
 export function ServerInfo(arg1:steam.Region,arg2:string):Promise<steam.ServerInfo>;
 
-export function Servers(arg1:steam.ID):Promise<{[key: string]: steam.Region}>;
+export function ServerPlayers(arg1:string):Promise<Array<steam.ServerPlayer>>;
+
+export function ServerPopulation(arg1:string,arg2:string):Promise<steam.PopHistory>;
+
+export function ServerRules(arg1:string):Promise<steam.ServerRules>;
+
+export function Servers(arg1:steam.ID,arg2:steam.Filter):Promise<{[key: string]: steam.Region}>;
+
+export function SetFavouriteNote(arg1:string,arg2:string):Promise<void>;
+
+export function SetFavouriteTags(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function SetPlayerVoice(arg1:string,arg2:string):Promise<void>;
+
+export function SetWatchRule(arg1:steam.WatchRule):Promise<steam.WatchRule>;
 
 export function Sounds():Promise<Array<sound.SoundInfo>>;
 
 export function State():Promise<appstate.AppState>;
 
+export function SynthesizeURL(arg1:string,arg2:string,arg3:config.TTSParams):Promise<string>;
+
+export function TTSMetrics():Promise<voicemod.TTSMetrics>;
+
+export function Unmute(arg1:string):Promise<void>;
+
 export function UpdateConfig(arg1:config.Config):Promise<void>;
+
+export function Voices():Promise<Array<sound.Voice>>;
+
+export function WatchRules():Promise<Array<steam.WatchRule>>;
@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddFavourite(arg1, arg2, arg3) {
+  return window['go']['main']['API']['AddFavourite'](arg1, arg2, arg3);
+}
+
 export function AppAddr() {
   return window['go']['main']['API']['AppAddr']();
 }
@@ -14,10 +18,22 @@ export function Error() {
   return window['go']['main']['API']['Error']();
 }
 
+export function Explain(arg1) {
+  return window['go']['main']['API']['Explain'](arg1);
+}
+
+export function Favourites() {
+  return window['go']['main']['API']['Favourites']();
+}
+
 export function Games() {
   return window['go']['main']['API']['Games']();
 }
 
+export function ImportSteamServers() {
+  return window['go']['main']['API']['ImportSteamServers']();
+}
+
 export function LaunchOptions(arg1, arg2) {
   return window['go']['main']['API']['LaunchOptions'](arg1, arg2);
 }
@@ -26,6 +42,18 @@ export function Log(arg1) {
   return window['go']['main']['API']['Log'](arg1);
 }
 
+export function Mutes() {
+  return window['go']['main']['API']['Mutes']();
+}
+
+export function PlayerTTSParams(arg1) {
+  return window['go']['main']['API']['PlayerTTSParams'](arg1);
+}
+
+export function PlayerVoice(arg1) {
+  return window['go']['main']['API']['PlayerVoice'](arg1);
+}
+
 export function Presence() {
   return window['go']['main']['API']['Presence']();
 }
@@ -34,12 +62,60 @@ export function Profile(arg1, arg2) {
   return window['go']['main']['API']['Profile'](arg1, arg2);
 }
 
+export function RemoveFavourite(arg1) {
+  return window['go']['main']['API']['RemoveFavourite'](arg1);
+}
+
+export function RemoveWatchRule(arg1) {
+  return window['go']['main']['API']['RemoveWatchRule'](arg1);
+}
+
+export function Rules() {
+  return window['go']['main']['API']['Rules']();
+}
+
+export function ScanServers(arg1) {
+  return window['go']['main']['API']['ScanServers'](arg1);
+}
+
+export function ServerHistory() {
+  return window['go']['main']['API']['ServerHistory']();
+}
+
 export function ServerInfo(arg1, arg2) {
   return window['go']['main']['API']['ServerInfo'](arg1, arg2);
 }
 
-export function Servers(arg1) {
-  return window['go']['main']['API']['Servers'](arg1);
+export function ServerPlayers(arg1) {
+  return window['go']['main']['API']['ServerPlayers'](arg1);
+}
+
+export function ServerPopulation(arg1, arg2) {
+  return window['go']['main']['API']['ServerPopulation'](arg1, arg2);
+}
+
+export function ServerRules(arg1) {
+  return window['go']['main']['API']['ServerRules'](arg1);
+}
+
+export function Servers(arg1, arg2) {
+  return window['go']['main']['API']['Servers'](arg1, arg2);
+}
+
+export function SetFavouriteNote(arg1, arg2) {
+  return window['go']['main']['API']['SetFavouriteNote'](arg1, arg2);
+}
+
+export function SetFavouriteTags(arg1, arg2) {
+  return window['go']['main']['API']['SetFavouriteTags'](arg1, arg2);
+}
+
+export function SetPlayerVoice(arg1, arg2) {
+  return window['go']['main']['API']['SetPlayerVoice'](arg1, arg2);
+}
+
+export function SetWatchRule(arg1) {
+  return window['go']['main']['API']['SetWatchRule'](arg1);
 }
 
 export function Sounds() {
@@ -50,6 +126,26 @@ export function State() {
   return window['go']['main']['API']['State']();
 }
 
+export function SynthesizeURL(arg1, arg2, arg3) {
+  return window['go']['main']['API']['SynthesizeURL'](arg1, arg2, arg3);
+}
+
+export function TTSMetrics() {
+  return window['go']['main']['API']['TTSMetrics']();
+}
+
+export function Unmute(arg1) {
+  return window['go']['main']['API']['Unmute'](arg1);
+}
+
 export function UpdateConfig(arg1) {
   return window['go']['main']['API']['UpdateConfig'](arg1);
 }
+
+export function Voices() {
+  return window['go']['main']['API']['Voices']();
+}
+
+export function WatchRules() {
+  return window['go']['main']['API']['WatchRules']();
+}
@@ -92,6 +92,16 @@ export namespace appstate {
 	    includeUsernames: {[key: string]: boolean};
 	    excludeUsernames: {[key: string]: boolean};
 	    hosts: {[key: string]: boolean};
+	    binds: {[key: string]: string};
+	    pronounce: {[key: string]: string};
+	    voices: {[key: string]: string};
+	    voiceParams: {[key: string]: config.TTSParams};
+	    ttsVoices: {[key: string]: config.TTSVoice};
+	    playerParams: {[key: string]: config.TTSParams};
+	    filter: config.FilterConfig;
+	    normalize: config.NormalizeConfig;
+	    pipeline: string[];
+	    spam: config.SpamConfig;
 	    firstVoice: string;
 	    logLevel: string;
 	    // Go type: config
@@ -122,6 +132,16 @@ export namespace appstate {
 	        this.includeUsernames = source["includeUsernames"];
 	        this.excludeUsernames = source["excludeUsernames"];
 	        this.hosts = source["hosts"];
+	        this.binds = source["binds"];
+	        this.pronounce = source["pronounce"];
+	        this.voices = source["voices"];
+	        this.voiceParams = this.convertValues(source["voiceParams"], config.TTSParams, true);
+	        this.ttsVoices = this.convertValues(source["ttsVoices"], config.TTSVoice, true);
+	        this.playerParams = this.convertValues(source["playerParams"], config.TTSParams, true);
+	        this.filter = this.convertValues(source["filter"], config.FilterConfig);
+	        this.normalize = this.convertValues(source["normalize"], config.NormalizeConfig);
+	        this.pipeline = source["pipeline"];
+	        this.spam = this.convertValues(source["spam"], config.SpamConfig);
 	        this.firstVoice = source["firstVoice"];
 	        this.logLevel = source["logLevel"];
 	        this.rateLimit = this.convertValues(source["rateLimit"], null);
@@ -155,6 +175,232 @@ export namespace appstate {
 
 export namespace config {
 	
+	export class SpamConfig {
+	    enabled?: boolean;
+	    // Go type: Dur
+	    window: any;
+	    // Go type: Dur
+	    floodWindow: any;
+	    floodMessages: number;
+	    globalFloodMessages: number;
+	    // Go type: Dur
+	    duplicateWindow: any;
+	    similarity: number;
+	    maxLength: number;
+	    maxCaps: number;
+	    strikes: number;
+	    // Go type: Dur
+	    strikeWindow: any;
+	    // Go type: Dur
+	    muteDuration: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpamConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.window = this.convertValues(source["window"], null);
+	        this.floodWindow = this.convertValues(source["floodWindow"], null);
+	        this.floodMessages = source["floodMessages"];
+	        this.globalFloodMessages = source["globalFloodMessages"];
+	        this.duplicateWindow = this.convertValues(source["duplicateWindow"], null);
+	        this.similarity = source["similarity"];
+	        this.maxLength = source["maxLength"];
+	        this.maxCaps = source["maxCaps"];
+	        this.strikes = source["strikes"];
+	        this.strikeWindow = this.convertValues(source["strikeWindow"], null);
+	        this.muteDuration = this.convertValues(source["muteDuration"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NormalizeConfig {
+	    numbers?: boolean;
+	    links?: boolean;
+	    emoji?: boolean;
+	    repeats?: boolean;
+	    caps?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new NormalizeConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.numbers = source["numbers"];
+	        this.links = source["links"];
+	        this.emoji = source["emoji"];
+	        this.repeats = source["repeats"];
+	        this.caps = source["caps"];
+	    }
+	}
+	export class FilterPolicy {
+	    enabled?: boolean;
+	    action: string;
+	    rules: FilterRule[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FilterPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.action = source["action"];
+	        this.rules = this.convertValues(source["rules"], FilterRule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FilterRule {
+	    pattern: string;
+	    match: string;
+	    action: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FilterRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pattern = source["pattern"];
+	        this.match = source["match"];
+	        this.action = source["action"];
+	    }
+	}
+	export class FilterConfig {
+	    enabled?: boolean;
+	    action: string;
+	    rules: FilterRule[];
+	    games: {[key: string]: FilterPolicy};
+	    servers: {[key: string]: FilterPolicy};
+	
+	    static createFrom(source: any = {}) {
+	        return new FilterConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.action = source["action"];
+	        this.rules = this.convertValues(source["rules"], FilterRule);
+	        this.games = this.convertValues(source["games"], FilterPolicy, true);
+	        this.servers = this.convertValues(source["servers"], FilterPolicy, true);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TTSVoice {
+	    backend: string;
+	    model: string;
+	    voice: string;
+	    url: string;
+	    exe: string;
+	    language: string;
+	    // Go type: Dur
+	    timeout: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new TTSVoice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.backend = source["backend"];
+	        this.model = source["model"];
+	        this.voice = source["voice"];
+	        this.url = source["url"];
+	        this.exe = source["exe"];
+	        this.language = source["language"];
+	        this.timeout = this.convertValues(source["timeout"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TTSParams {
+	    rate: number;
+	    variability: number;
+	    cadence: number;
+	    speaker: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TTSParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rate = source["rate"];
+	        this.variability = source["variability"];
+	        this.cadence = source["cadence"];
+	        this.speaker = source["speaker"];
+	    }
+	}
 	export class ConnInfo {
 	    host: string;
 	    port: number;
@@ -184,6 +430,16 @@ export namespace config {
 	    includeUsernames: {[key: string]: boolean};
 	    excludeUsernames: {[key: string]: boolean};
 	    hosts: {[key: string]: boolean};
+	    binds: {[key: string]: string};
+	    pronounce: {[key: string]: string};
+	    voices: {[key: string]: string};
+	    voiceParams: {[key: string]: TTSParams};
+	    ttsVoices: {[key: string]: TTSVoice};
+	    playerParams: {[key: string]: TTSParams};
+	    filter: FilterConfig;
+	    normalize: NormalizeConfig;
+	    pipeline: string[];
+	    spam: SpamConfig;
 	    firstVoice: string;
 	    logLevel: string;
 	    // Go type: Dur
@@ -211,6 +467,16 @@ export namespace config {
 	        this.includeUsernames = source["includeUsernames"];
 	        this.excludeUsernames = source["excludeUsernames"];
 	        this.hosts = source["hosts"];
+	        this.binds = source["binds"];
+	        this.pronounce = source["pronounce"];
+	        this.voices = source["voices"];
+	        this.voiceParams = this.convertValues(source["voiceParams"], TTSParams, true);
+	        this.ttsVoices = this.convertValues(source["ttsVoices"], TTSVoice, true);
+	        this.playerParams = this.convertValues(source["playerParams"], TTSParams, true);
+	        this.filter = this.convertValues(source["filter"], FilterConfig);
+	        this.normalize = this.convertValues(source["normalize"], NormalizeConfig);
+	        this.pipeline = source["pipeline"];
+	        this.spam = this.convertValues(source["spam"], SpamConfig);
 	        this.firstVoice = source["firstVoice"];
 	        this.logLevel = source["logLevel"];
 	        this.rateLimit = this.convertValues(source["rateLimit"], null);
@@ -239,6 +505,13 @@ export namespace config {
 		    return a;
 		}
 	}
+	
+	
+	
+	
+	
+	
+	
 
 }
 
@@ -265,104 +538,123 @@ export namespace logs {
 
 export namespace sound {
 	
-	export class SoundInfo {
-	    name: string;
+	export class RuleIssue {
+	    key: string;
+	    target: string;
+	    message: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SoundInfo(source);
+	        return new RuleIssue(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
+	        this.key = source["key"];
+	        this.target = source["target"];
+	        this.message = source["message"];
 	    }
 	}
-
-}
-
-export namespace steam {
+	export class RulesInfo {
+	    rules: translate.Rules;
+	    issues: RuleIssue[];
 	
-	export class GameInfo {
-	    id: number;
-	    title: string;
-	    dirName: string;
-	    iconURI: string;
-	    heroURI: string;
-	    mapImageURL: string;
-	    bgVideoURL: string;
-	    mapNames: string[];
-	    mapImageURLs: string[];
+	    static createFrom(source: any = {}) {
+	        return new RulesInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rules = this.convertValues(source["rules"], translate.Rules);
+	        this.issues = this.convertValues(source["issues"], RuleIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SoundInfo {
+	    name: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new GameInfo(source);
+	        return new SoundInfo(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.title = source["title"];
-	        this.dirName = source["dirName"];
-	        this.iconURI = source["iconURI"];
-	        this.heroURI = source["heroURI"];
-	        this.mapImageURL = source["mapImageURL"];
-	        this.bgVideoURL = source["bgVideoURL"];
-	        this.mapNames = source["mapNames"];
-	        this.mapImageURLs = source["mapImageURLs"];
+	        this.name = source["name"];
 	    }
 	}
-	export class Profile {
-	    userID: number;
-	    avatarURI: string;
-	    username: string;
-	    clan: string;
+	export class Voice {
 	    name: string;
+	    backend: string;
+	    model: string;
+	    speaker: string;
+	    speakerID: number;
+	    language: string;
+	    quality: string;
+	    sampleRate: number;
+	    embedded: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new Profile(source);
+	        return new Voice(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.userID = source["userID"];
-	        this.avatarURI = source["avatarURI"];
-	        this.username = source["username"];
-	        this.clan = source["clan"];
 	        this.name = source["name"];
+	        this.backend = source["backend"];
+	        this.model = source["model"];
+	        this.speaker = source["speaker"];
+	        this.speakerID = source["speakerID"];
+	        this.language = source["language"];
+	        this.quality = source["quality"];
+	        this.sampleRate = source["sampleRate"];
+	        this.embedded = source["embedded"];
 	    }
 	}
-	export class ServerInfo {
+
+}
+
+export namespace steam {
+	
+	export class Favourite {
 	    addr: string;
 	    name: string;
-	    players: number;
-	    bots: number;
-	    restricted: boolean;
-	    ping: number;
-	    map: string;
-	    game: string;
-	    maxPlayers: number;
-	    region: number;
-	    country: string;
+	    gameID: number;
+	    tags: string[];
+	    note: string;
 	    // Go type: time
-	    ts: any;
+	    added: any;
+	    // Go type: time
+	    lastPlayed: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new ServerInfo(source);
+	        return new Favourite(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.addr = source["addr"];
 	        this.name = source["name"];
-	        this.players = source["players"];
-	        this.bots = source["bots"];
-	        this.restricted = source["restricted"];
-	        this.ping = source["ping"];
-	        this.map = source["map"];
-	        this.game = source["game"];
-	        this.maxPlayers = source["maxPlayers"];
-	        this.region = source["region"];
-	        this.country = source["country"];
-	        this.ts = this.convertValues(source["ts"], null);
+	        this.gameID = source["gameID"];
+	        this.tags = source["tags"];
+	        this.note = source["note"];
+	        this.added = this.convertValues(source["added"], null);
+	        this.lastPlayed = this.convertValues(source["lastPlayed"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -383,6 +675,625 @@ export namespace steam {
 		    return a;
 		}
 	}
+	export class Filter {
+	    dedicated: boolean;
+	    secure: boolean;
+	    gameDir: string;
+	    map: string;
+	    notEmpty: boolean;
+	    notFull: boolean;
+	    noPassword: boolean;
+	    nameMatch: string;
+	    gameType: string[];
+	    nor?: Filter;
+	    nand?: Filter;
+	
+	    static createFrom(source: any = {}) {
+	        return new Filter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dedicated = source["dedicated"];
+	        this.secure = source["secure"];
+	        this.gameDir = source["gameDir"];
+	        this.map = source["map"];
+	        this.notEmpty = source["notEmpty"];
+	        this.notFull = source["notFull"];
+	        this.noPassword = source["noPassword"];
+	        this.nameMatch = source["nameMatch"];
+	        this.gameType = source["gameType"];
+	        this.nor = this.convertValues(source["nor"], Filter);
+	        this.nand = this.convertValues(source["nand"], Filter);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GameInfo {
+	    id: number;
+	    title: string;
+	    dirName: string;
+	    iconURI: string;
+	    heroURI: string;
+	    mapImageURL: string;
+	    bgVideoURL: string;
+	    mapNames: string[];
+	    mapImageURLs: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GameInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.dirName = source["dirName"];
+	        this.iconURI = source["iconURI"];
+	        this.heroURI = source["heroURI"];
+	        this.mapImageURL = source["mapImageURL"];
+	        this.bgVideoURL = source["bgVideoURL"];
+	        this.mapNames = source["mapNames"];
+	        this.mapImageURLs = source["mapImageURLs"];
+	    }
+	}
+	export class HistoryEntry {
+	    addr: string;
+	    name: string;
+	    gameID: number;
+	    // Go type: time
+	    lastPlayed: any;
+	    joins: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.addr = source["addr"];
+	        this.name = source["name"];
+	        this.gameID = source["gameID"];
+	        this.lastPlayed = this.convertValues(source["lastPlayed"], null);
+	        this.joins = source["joins"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MapPlay {
+	    map: string;
+	    // Go type: time
+	    start: any;
+	    // Go type: time
+	    end: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new MapPlay(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.map = source["map"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PopSample {
+	    // Go type: time
+	    ts: any;
+	    players: number;
+	    bots: number;
+	    ping: number;
+	    map: string;
+	    samples: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PopSample(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ts = this.convertValues(source["ts"], null);
+	        this.players = source["players"];
+	        this.bots = source["bots"];
+	        this.ping = source["ping"];
+	        this.map = source["map"];
+	        this.samples = source["samples"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PopHistory {
+	    addr: string;
+	    samples: PopSample[];
+	    rotation: MapPlay[];
+	    byHour: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PopHistory(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.addr = source["addr"];
+	        this.samples = this.convertValues(source["samples"], PopSample);
+	        this.rotation = this.convertValues(source["rotation"], MapPlay);
+	        this.byHour = source["byHour"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Profile {
+	    userID: number;
+	    avatarURI: string;
+	    username: string;
+	    clan: string;
+	    name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.userID = source["userID"];
+	        this.avatarURI = source["avatarURI"];
+	        this.username = source["username"];
+	        this.clan = source["clan"];
+	        this.name = source["name"];
+	    }
+	}
+	export class ServerInfo {
+	    addr: string;
+	    name: string;
+	    players: number;
+	    bots: number;
+	    restricted: boolean;
+	    ping: number;
+	    map: string;
+	    game: string;
+	    maxPlayers: number;
+	    region: number;
+	    country: string;
+	    // Go type: time
+	    ts: any;
+	    appID: number;
+	    version: string;
+	    tags: string[];
+	    steamID: string;
+	    sourceTVPort: number;
+	    sourceTVName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.addr = source["addr"];
+	        this.name = source["name"];
+	        this.players = source["players"];
+	        this.bots = source["bots"];
+	        this.restricted = source["restricted"];
+	        this.ping = source["ping"];
+	        this.map = source["map"];
+	        this.game = source["game"];
+	        this.maxPlayers = source["maxPlayers"];
+	        this.region = source["region"];
+	        this.country = source["country"];
+	        this.ts = this.convertValues(source["ts"], null);
+	        this.appID = source["appID"];
+	        this.version = source["version"];
+	        this.tags = source["tags"];
+	        this.steamID = source["steamID"];
+	        this.sourceTVPort = source["sourceTVPort"];
+	        this.sourceTVName = source["sourceTVName"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ServerPlayer {
+	    name: string;
+	    score: number;
+	    duration: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerPlayer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.score = source["score"];
+	        this.duration = source["duration"];
+	    }
+	}
+	export class WatchRule {
+	    id: string;
+	    addr: string;
+	    disabled: boolean;
+	    minPlayers: number;
+	    map: string;
+	    playerName: string;
+	    playerID: number;
+	    notify: boolean;
+	    webhook: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new WatchRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.addr = source["addr"];
+	        this.disabled = source["disabled"];
+	        this.minPlayers = source["minPlayers"];
+	        this.map = source["map"];
+	        this.playerName = source["playerName"];
+	        this.playerID = source["playerID"];
+	        this.notify = source["notify"];
+	        this.webhook = source["webhook"];
+	    }
+	}
+
+}
+
+export namespace translate {
+	
+	export class Style {
+	    emphasis: boolean;
+	    slow: boolean;
+	    voice: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Style(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.emphasis = source["emphasis"];
+	        this.slow = source["slow"];
+	        this.voice = source["voice"];
+	    }
+	}
+	export class Segment {
+	    text: string;
+	    bleep: boolean;
+	    sound: boolean;
+	    style: Style;
+	
+	    static createFrom(source: any = {}) {
+	        return new Segment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.bleep = source["bleep"];
+	        this.sound = source["sound"];
+	        this.style = this.convertValues(source["style"], Style);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Message {
+	    segments: Segment[];
+	    dropped: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Message(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.segments = this.convertValues(source["segments"], Segment);
+	        this.dropped = source["dropped"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Rewrite {
+	    pattern: string;
+	    replace: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Rewrite(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pattern = source["pattern"];
+	        this.replace = source["replace"];
+	    }
+	}
+	export class Rules {
+	    translations: {[key: string]: string[]};
+	    substitutes: {[key: string]: string[]};
+	    rewrites: Rewrite[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Rules(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.translations = source["translations"];
+	        this.substitutes = source["substitutes"];
+	        this.rewrites = this.convertValues(source["rewrites"], Rewrite);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Step {
+	    stage: string;
+	    text: string;
+	    changed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Step(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stage = source["stage"];
+	        this.text = source["text"];
+	        this.changed = source["changed"];
+	    }
+	}
+	
+	export class Trace {
+	    input: string;
+	    steps: Step[];
+	    output: Message;
+	
+	    static createFrom(source: any = {}) {
+	        return new Trace(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.input = source["input"];
+	        this.steps = this.convertValues(source["steps"], Step);
+	        this.output = this.convertValues(source["output"], Message);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace voicemod {
+	
+	export class Mute {
+	    key: string;
+	    username: string;
+	    reason: string;
+	    // Go type: time
+	    until: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Mute(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.username = source["username"];
+	        this.reason = source["reason"];
+	        this.until = this.convertValues(source["until"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TTSMetrics {
+	    queued: number;
+	    running: number;
+	    completed: number;
+	    failed: number;
+	    timedOut: number;
+	    cancelled: number;
+	    dropped: number;
+	    latency: number;
+	    maxLatency: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TTSMetrics(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.queued = source["queued"];
+	        this.running = source["running"];
+	        this.completed = source["completed"];
+	        this.failed = source["failed"];
+	        this.timedOut = source["timedOut"];
+	        this.cancelled = source["cancelled"];
+	        this.dropped = source["dropped"];
+	        this.latency = source["latency"];
+	        this.maxLatency = source["maxLatency"];
+	    }
+	}
 
 }
 
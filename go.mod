@@ -9,6 +9,8 @@ require (
 	github.com/amitybell/memio v0.0.0-20231117101439-b2d22ecd10f6
 	github.com/amitybell/piper v0.0.0-20240130090909-b170e42b1e09
 	github.com/amitybell/piper-asset v0.0.0-20231030194325-d36a29e3b1fd
+	github.com/amitybell/piper-bin-linux v0.0.0-20231118093037-92b3de178ad8
+	github.com/amitybell/piper-bin-windows v0.0.0-20231118093113-cc2cef2f6b74
 	github.com/amitybell/piper-voice-alan v0.0.0-20231118093148-059963c24dbd
 	github.com/amitybell/piper-voice-jenny v0.0.0-20231118093224-dcf0d49e46b7
 	github.com/andygrunwald/vdf v1.1.0
@@ -17,11 +19,13 @@ require (
 	github.com/gabriel-vasile/mimetype v1.4.3
 	github.com/gofrs/uuid/v5 v5.0.0
 	github.com/gopxl/beep v1.3.0
+	github.com/klauspost/compress v1.17.5
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/vmihailenco/msgpack/v5 v5.4.1
 	github.com/wailsapp/wails/v2 v2.7.1
 	github.com/ziutek/telnet v0.0.0-20180329124119-c3b780dc415b
 	golang.org/x/sys v0.16.0
+	golang.org/x/text v0.14.0
 	golang.org/x/time v0.5.0
 	lukechampine.com/frand v1.4.2
 )
@@ -29,8 +33,6 @@ require (
 require (
 	github.com/DataDog/zstd v1.4.5 // indirect
 	github.com/aead/chacha20 v0.0.0-20180709150244-8b13a72661da // indirect
-	github.com/amitybell/piper-bin-linux v0.0.0-20231118093037-92b3de178ad8 // indirect
-	github.com/amitybell/piper-bin-windows v0.0.0-20231118093113-cc2cef2f6b74 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/bep/debounce v1.2.1 // indirect
 	github.com/cespare/xxhash/v2 v2.2.0 // indirect
@@ -51,7 +53,6 @@ require (
 	github.com/jchv/go-winloader v0.0.0-20210711035445-715c2860da7e // indirect
 	github.com/jfreymuth/oggvorbis v1.0.5 // indirect
 	github.com/jfreymuth/vorbis v1.0.2 // indirect
-	github.com/klauspost/compress v1.17.5 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/labstack/echo/v4 v4.10.2 // indirect
@@ -87,6 +88,5 @@ require (
 	golang.org/x/exp v0.0.0-20230626212559-97b1e661b5df // indirect
 	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sync v0.6.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
 )
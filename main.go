@@ -5,17 +5,26 @@ import (
 
 	"github.com/amitybell/srcvox/config"
 	"github.com/amitybell/srcvox/logs"
+	"github.com/amitybell/srcvox/rng"
 )
 
 var (
 	paths = config.DefaultPaths
 	Logs  = logs.AppLogger()
+
+	seed = flag.Int64("seed", 0, "Seed the random number generator, for reproducible behaviour")
 )
 
 func main() {
 	defer Logs.Close()
 
 	flag.Parse()
+	// any seed can be requested, including 0
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			rng.Seed(*seed)
+		}
+	})
 
 	app := NewApp(paths)
 	defer app.Close()
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amitybell/srcvox/steam"
+	"github.com/amitybell/srcvox/store"
+	"github.com/amitybell/srcvox/translate"
+)
+
+// playerKey returns the store key under prefix for the player
+//
+// Players are keyed by steam ID if it's known, so the entry survives renames.
+func playerKey(prefix string, id steam.ID, username string) string {
+	if id != 0 {
+		return prefix + "/id/" + id.String()
+	}
+	return prefix + "/user/" + username
+}
+
+// playerEntry looks up the player in m, which is keyed by steam ID or username
+//
+// Entries keyed by steam ID take precedence over those keyed by username.
+func playerEntry[V any](m map[string]V, id steam.ID, username string) (V, bool) {
+	if id != 0 {
+		for k, v := range m {
+			if kid, err := steam.ParseID(k); err == nil && kid == id {
+				return v, true
+			}
+		}
+	}
+	v, ok := m[username]
+	return v, ok
+}
+
+// SpokenName returns the name to say for the player username
+//
+// In order of precedence, it's the entry in Config.Pronounce,
+// the name the player set with !name, or translate.SpeakableName.
+func (app *App) SpokenName(username string) string {
+	state := app.State()
+	id := state.Presence.PlayerID(username)
+	if s, ok := playerEntry(state.Pronounce, id, username); ok {
+		return s
+	}
+	if s, _ := store.Get[string](app.DB, playerKey("/names", id, username)); s != "" {
+		return s
+	}
+	return translate.SpeakableName(username)
+}
+
+// SetSpokenName sets the name to say for the player username
+//
+// If name is empty, the player's name is reset.
+func (app *App) SetSpokenName(username, name string) error {
+	name = strings.Join(strings.Fields(name), " ")
+	if n := translate.MaxNameLen; len(name) > n {
+		return fmt.Errorf("SetSpokenName(%s): Name is longer than %d characters", username, n)
+	}
+	id := app.State().Presence.PlayerID(username)
+	if err := store.Put(app.DB, playerKey("/names", id, username), name); err != nil {
+		return fmt.Errorf("SetSpokenName(%s): %w", username, err)
+	}
+	return nil
+}
@@ -1,11 +1,82 @@
 package rng
 
 import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
 	"lukechampine.com/frand"
 )
 
+var (
+	src atomic.Pointer[Source]
+)
+
+// Source is a source of random numbers
+type Source interface {
+	// Uint64n returns a number in [0, n)
+	Uint64n(n uint64) uint64
+}
+
+type cryptoSource struct{}
+
+func (cryptoSource) Uint64n(n uint64) uint64 {
+	return frand.Uint64n(n)
+}
+
+// CryptoSource returns the default source, which is cryptographically secure and can't be seeded
+func CryptoSource() Source {
+	return cryptoSource{}
+}
+
+type seededSource struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (s *seededSource) Uint64n(n uint64) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n <= math.MaxInt64 {
+		return uint64(s.r.Int63n(int64(n)))
+	}
+	return s.r.Uint64() % n
+}
+
+// NewSeeded returns a deterministic source, that returns the same sequence of numbers for the same seed
+func NewSeeded(seed int64) Source {
+	return &seededSource{r: rand.New(rand.NewSource(seed))}
+}
+
+// SetSource installs s as the source used by the package functions, and returns the previous source
+//
+// If s is nil, CryptoSource is installed.
+func SetSource(s Source) Source {
+	if s == nil {
+		s = CryptoSource()
+	}
+	if p := src.Swap(&s); p != nil {
+		return *p
+	}
+	return CryptoSource()
+}
+
+// Seed installs a seeded source, see NewSeeded
+func Seed(seed int64) {
+	SetSource(NewSeeded(seed))
+}
+
+func source() Source {
+	if p := src.Load(); p != nil {
+		return *p
+	}
+	return CryptoSource()
+}
+
 func Intn(n int) int {
-	return int(frand.Intn(n))
+	return int(source().Uint64n(uint64(n)))
 }
 
 func Int() int {
@@ -29,8 +100,9 @@ func Elem[T any](s []T) T {
 
 func Shuffle[S ~[]E, E any](s S) S {
 	s = append([]E(nil), s...)
-	frand.Shuffle(len(s), func(i, j int) {
+	for i := len(s) - 1; i > 0; i-- {
+		j := Intn(i + 1)
 		s[i], s[j] = s[j], s[i]
-	})
+	}
 	return s
 }
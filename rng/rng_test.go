@@ -0,0 +1,28 @@
+package rng
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeeded(t *testing.T) {
+	defer SetSource(nil)
+
+	draw := func() []int {
+		l := []int{Intn(100), Range(10, 20), Int()}
+		return append(l, Shuffle([]int{1, 2, 3, 4, 5, 6, 7, 8})...)
+	}
+
+	Seed(42)
+	a := draw()
+	Seed(42)
+	b := draw()
+	if !slices.Equal(a, b) {
+		t.Fatalf("Expected the same seed to give the same numbers; Got %v and %v", a, b)
+	}
+
+	Seed(43)
+	if c := draw(); slices.Equal(a, c) {
+		t.Fatalf("Expected a different seed to give different numbers; Got %v", c)
+	}
+}
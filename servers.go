@@ -0,0 +1,125 @@
+package main
+
+import (
+	"time"
+
+	"github.com/amitybell/srcvox/appstate"
+	"github.com/amitybell/srcvox/steam"
+	"github.com/amitybell/srcvox/store"
+)
+
+const (
+	// serverBookImportedKey is set once Steam's server browser history has been imported
+	serverBookImportedKey = "/servers/imported"
+
+	// populationSampleEvery is how often the population of favourite and recently joined servers is sampled
+	populationSampleEvery = 5 * time.Minute
+)
+
+// serverList returns the servers from the master server and on the LAN that match filter
+//
+// LAN servers are in the steam.LAN region.
+func (app *App) serverList(gameID steam.ID, filter steam.Filter) (map[string]steam.Region, error) {
+	state := app.State()
+	lan := make(chan map[string]steam.Region, 1)
+	go func() {
+		m, err := steam.QueryLANServerList(app.ctx, app.DB, state.ServerInfoMaxAge.D, gameID, filter, steam.LANOptions{})
+		if err != nil {
+			Logs.Println("serverList:", err)
+		}
+		lan <- m
+	}()
+
+	servers, err := steam.QueryServerList(app.DB, state.ServerListMaxAge.D, gameID, filter)
+	lanServers := <-lan
+	if len(lanServers) == 0 {
+		return servers, err
+	}
+	if err != nil {
+		// the LAN servers are still worth showing
+		Logs.Println("serverList:", err)
+	}
+	m := make(map[string]steam.Region, len(servers)+len(lanServers))
+	for addr, r := range servers {
+		m[addr] = r
+	}
+	for addr, r := range lanServers {
+		m[addr] = r
+	}
+	return m, nil
+}
+
+// initServerBook opens the favourites and join history, importing Steam's on the first run
+func (app *App) initServerBook() {
+	app.servers = steam.NewServerBook(app.DB)
+	app.watcher = &steam.Watcher{
+		DB:            app.DB,
+		PlayersMaxAge: app.State().ServerInfoMaxAge.D,
+		Alert:         app.watchAlert,
+	}
+	if done, _ := store.Get[bool](app.DB, serverBookImportedKey); done {
+		return
+	}
+	app.importServerBrowserHist()
+}
+
+// startPopulationSampler samples the population of the servers in the server book in the background
+func (app *App) startPopulationSampler() {
+	pop := &steam.PopulationSampler{
+		DB:    app.DB,
+		Book:  app.servers,
+		Every: populationSampleEvery,
+		// keep the background queries light
+		Scan:  steam.ScanOptions{Concurrency: 8, Rate: 20},
+		Watch: app.watcher,
+	}
+	go pop.Run(app.ctx)
+}
+
+// importServerBrowserHist imports the favourites and history from Steam's serverbrowser_hist.vdf
+//
+// Once a file is imported, it's not imported automatically on the next start.
+// If Steam isn't installed yet, it's tried again on the next start.
+func (app *App) importServerBrowserHist() (favs int, hist int) {
+	imported := false
+	for _, fn := range steam.FindServerBrowserHist() {
+		f, h, err := app.servers.ImportServerBrowserHist(fn)
+		if err != nil {
+			Logs.Println("importServerBrowserHist:", err)
+			continue
+		}
+		Logs.Printf("importServerBrowserHist: %d favourites and %d history entries from %s", f, h, fn)
+		favs += f
+		hist += h
+		imported = true
+	}
+	if imported {
+		_ = store.Put(app.DB, serverBookImportedKey, true)
+	}
+	return favs, hist
+}
+
+// joinServer adds the server the user joined to the history
+func (app *App) joinServer(server string, gameID steam.ID, ts time.Time) {
+	// this also keeps the cached server info fresh for TranslateVars
+	inf, _, _ := steam.QueryServerInfo(app.DB, app.State().ServerInfoMaxAge.D, 0, server)
+	if err := app.servers.Join(server, inf.Name, gameID, ts); err != nil {
+		Logs.Println("joinServer:", err)
+	}
+}
+
+// watchAlert emits the alert, and a notification if its rule requests one
+func (app *App) watchAlert(alert steam.WatchAlert) {
+	Logs.Println("watchAlert:", alert.Message)
+	app.Emit(appstate.SvWatchAlert, alert)
+	if alert.Rule.Notify {
+		app.Emit(appstate.SvNotification, alert)
+	}
+}
+
+// seePlayers records the players seen in the status table, so watch rules can find them by SteamID
+func (app *App) seePlayers(l []steam.Profile, ts time.Time) {
+	if err := app.watcher.SeePlayers(l, ts); err != nil {
+		Logs.Println("seePlayers:", err)
+	}
+}
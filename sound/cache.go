@@ -0,0 +1,55 @@
+package sound
+
+import (
+	"sync"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+const (
+	synthCacheSize = 128
+)
+
+var (
+	synthCache = newWavCache(synthCacheSize)
+)
+
+// wavCache is a fixed-size cache of synthesized speech, that evicts the oldest entries first
+type wavCache struct {
+	mu   sync.Mutex
+	size int
+	keys []string
+	m    map[string][]byte
+}
+
+func newWavCache(size int) *wavCache {
+	return &wavCache{size: size, m: map[string][]byte{}}
+}
+
+func (c *wavCache) get(k string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wav, ok := c.m[k]
+	return wav, ok
+}
+
+func (c *wavCache) put(k string, wav []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.m[k]; ok {
+		return
+	}
+	if len(c.keys) >= c.size {
+		delete(c.m, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.keys = append(c.keys, k)
+	c.m[k] = wav
+}
+
+// synthCacheKey returns the cache key for voice speaking text with params p
+func synthCacheKey(voice *Voice, p config.TTSParams, text string) string {
+	return voice.Backend + "\x00" + voice.Model + "\x00" + voice.Name + "\x00" + p.Clamp().Key() + "\x00" + text
+}
@@ -0,0 +1,60 @@
+package sound
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+const (
+	defaultEspeakVoice = "en"
+
+	// espeakRate is espeak-ng's default speed, in words per minute
+	espeakRate = 175
+	// espeakPitch is espeak-ng's default pitch, in the range 0-99
+	espeakPitch = 50
+)
+
+// Espeak is a Synthesizer that runs espeak-ng
+//
+// Variability sets the pitch, and Speaker selects a voice variant e.g. `f3`.
+// Cadence isn't supported.
+type Espeak struct {
+	// Exe is the path to espeak-ng; it defaults to espeak-ng in PATH
+	Exe string
+	// Voice is the espeak-ng voice e.g. `en-us`; it defaults to `en`
+	Voice string
+}
+
+// args returns the espeak-ng args to synthesize text with params p
+//
+// If outputFn is empty, the wav file is written to stdout.
+func (es *Espeak) args(p config.TTSParams, outputFn string) []string {
+	voice := cmpOr(es.Voice, defaultEspeakVoice)
+	if p.Speaker != "" {
+		voice += "+" + p.Speaker
+	}
+	args := []string{"-v", voice}
+	p = p.Clamp()
+	if p.Rate > 0 {
+		args = append(args, "-s", strconv.Itoa(int(espeakRate*p.Rate)))
+	}
+	if p.Variability > 0 {
+		args = append(args, "-p", strconv.Itoa(min(int(espeakPitch*p.Variability), 99)))
+	}
+	if outputFn == "" {
+		return append(args, "--stdout")
+	}
+	return append(args, "-w", outputFn)
+}
+
+func (es *Espeak) Synthesize(ctx context.Context, text string, p config.TTSParams) ([]byte, error) {
+	exe := cmpOr(es.Exe, espeakExe)
+	wav, err := runTTS(ctx, exe, func(outputFn string) []string { return es.args(p, outputFn) }, text)
+	if err != nil {
+		return nil, fmt.Errorf("Espeak.Synthesize: %w", err)
+	}
+	return wav, nil
+}
@@ -0,0 +1,63 @@
+package sound
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amitybell/memio"
+	"github.com/amitybell/srcvox/audio"
+	"github.com/amitybell/srcvox/config"
+)
+
+// FakeSynthesizer is a Synthesizer for tests
+//
+// It returns a tone lasting 10ms per character, and records its calls.
+type FakeSynthesizer struct {
+	// Err is returned by Synthesize, if set
+	Err error
+	// Delay is how long Synthesize takes, unless its context is cancelled
+	Delay time.Duration
+
+	mu     sync.Mutex
+	texts  []string
+	params []config.TTSParams
+}
+
+func (fs *FakeSynthesizer) Synthesize(ctx context.Context, text string, p config.TTSParams) ([]byte, error) {
+	fs.mu.Lock()
+	fs.texts = append(fs.texts, text)
+	fs.params = append(fs.params, p)
+	fs.mu.Unlock()
+
+	if fs.Delay > 0 {
+		select {
+		case <-time.After(fs.Delay):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("FakeSynthesizer.Synthesize: %w", ctx.Err())
+		}
+	}
+	if fs.Err != nil {
+		return nil, fs.Err
+	}
+	dur := time.Duration(max(utf8.RuneCountInString(text), 1)) * 10 * time.Millisecond
+	au, err := audio.Tone(text, 440, dur, bleepFormat)
+	if err != nil {
+		return nil, fmt.Errorf("FakeSynthesizer.Synthesize: %w", err)
+	}
+	f := memio.NewFile(nil)
+	if _, err := au.Encode(0, 0, f, bleepFormat); err != nil {
+		return nil, fmt.Errorf("FakeSynthesizer.Synthesize: %w", err)
+	}
+	return f.Bytes(), nil
+}
+
+// Calls returns the texts and params passed to Synthesize
+func (fs *FakeSynthesizer) Calls() ([]string, []config.TTSParams) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return append([]string(nil), fs.texts...), append([]config.TTSParams(nil), fs.params...)
+}
@@ -0,0 +1,79 @@
+package sound
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+const (
+	defaultHTTPTimeout = 30 * time.Second
+
+	// maxHTTPWavSize limits the size of the responses from HTTP backends
+	maxHTTPWavSize = 64 << 20
+)
+
+// HTTPRequest is the body POSTed to HTTP backends
+type HTTPRequest struct {
+	Text  string `json:"text"`
+	Voice string `json:"voice"`
+	config.TTSParams
+}
+
+// HTTPSynthesizer is a Synthesizer for locally hosted engines
+//
+// It POSTs a JSON HTTPRequest to URL, and expects a wav file in response.
+type HTTPSynthesizer struct {
+	URL   string
+	Voice string
+	// Timeout defaults to 30 seconds
+	Timeout time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func (hs *HTTPSynthesizer) Synthesize(ctx context.Context, text string, p config.TTSParams) ([]byte, error) {
+	body, err := json.Marshal(HTTPRequest{Text: text, Voice: hs.Voice, TTSParams: p.Clamp()})
+	if err != nil {
+		return nil, fmt.Errorf("HTTPSynthesizer.Synthesize: %w", err)
+	}
+
+	cl := hs.Client
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	timeout := hs.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	cl = &http.Client{Transport: cl.Transport, Timeout: timeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hs.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("HTTPSynthesizer.Synthesize: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTPSynthesizer.Synthesize: %w", err)
+	}
+	defer resp.Body.Close()
+
+	wav, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPWavSize))
+	if err != nil {
+		return nil, fmt.Errorf("HTTPSynthesizer.Synthesize: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTPSynthesizer.Synthesize: %s: %s", resp.Status, bytes.TrimSpace(wav[:min(len(wav), 200)]))
+	}
+	if len(wav) < 12 || string(wav[:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("HTTPSynthesizer.Synthesize: The response is not a wav file")
+	}
+	return wav, nil
+}
@@ -0,0 +1,53 @@
+package sound
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+func TestHTTPSynthesizer(t *testing.T) {
+	wav, err := (&FakeSynthesizer{}).Synthesize(context.Background(), "hi", config.TTSParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got HTTPRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got.Text == "fail" {
+			http.Error(w, "no", http.StatusInternalServerError)
+			return
+		}
+		w.Write(wav)
+	}))
+	defer srv.Close()
+
+	hs := &HTTPSynthesizer{URL: srv.URL, Voice: "p225"}
+	s, err := hs.Synthesize(context.Background(), "hello", config.TTSParams{Rate: 2, Speaker: "x"})
+	if err != nil {
+		t.Fatalf("HTTPSynthesizer.Synthesize: %s", err)
+	}
+	if string(s) != string(wav) {
+		t.Fatalf("HTTPSynthesizer.Synthesize: Expected the server's wav file")
+	}
+	exp := HTTPRequest{Text: "hello", Voice: "p225", TTSParams: config.TTSParams{Rate: 2, Speaker: "x"}}
+	if got != exp {
+		t.Fatalf("HTTPSynthesizer.Synthesize: Expected request %+v; Got %+v", exp, got)
+	}
+
+	if _, err := hs.Synthesize(context.Background(), "fail", config.TTSParams{}); err == nil {
+		t.Fatalf("HTTPSynthesizer.Synthesize: Expected an error for a failed request")
+	}
+}
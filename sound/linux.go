@@ -0,0 +1,19 @@
+//go:build linux
+
+package sound
+
+import (
+	"syscall"
+
+	linux "github.com/amitybell/piper-bin-linux"
+)
+
+var (
+	// piperBinAsset is the piper binary installed by piper.New
+	piperBinAsset = linux.Asset
+
+	piperExe  = "piper"
+	espeakExe = "espeak-ng"
+
+	sysProcAttr *syscall.SysProcAttr
+)
@@ -0,0 +1,262 @@
+package sound
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/amitybell/piper"
+	asset "github.com/amitybell/piper-asset"
+	"github.com/amitybell/srcvox/config"
+)
+
+const (
+	ModelExt       = ".onnx"
+	ModelConfigExt = ".onnx.json"
+)
+
+// ModelConfig is the subset of a Piper model's .onnx.json config that we use
+type ModelConfig struct {
+	Audio struct {
+		SampleRate int    `json:"sample_rate"`
+		Quality    string `json:"quality"`
+	} `json:"audio"`
+	Espeak struct {
+		Voice string `json:"voice"`
+	} `json:"espeak"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	NumSpeakers  int              `json:"num_speakers"`
+	SpeakerIDMap map[string]int   `json:"speaker_id_map"`
+	PhonemeIDMap map[string][]int `json:"phoneme_id_map"`
+}
+
+// ReadModelConfig reads and validates the model config fn
+func ReadModelConfig(fn string) (ModelConfig, error) {
+	var mc ModelConfig
+	s, err := os.ReadFile(fn)
+	if err != nil {
+		return mc, fmt.Errorf("ReadModelConfig: %w", err)
+	}
+	if err := json.Unmarshal(s, &mc); err != nil {
+		return mc, fmt.Errorf("ReadModelConfig(%s): %w", fn, err)
+	}
+	switch {
+	case mc.Audio.SampleRate <= 0:
+		return mc, fmt.Errorf("ReadModelConfig(%s): Invalid sample rate %d", fn, mc.Audio.SampleRate)
+	case len(mc.PhonemeIDMap) == 0:
+		return mc, fmt.Errorf("ReadModelConfig(%s): Missing phoneme_id_map", fn)
+	case mc.NumSpeakers > 1 && len(mc.SpeakerIDMap) == 0:
+		return mc, fmt.Errorf("ReadModelConfig(%s): Missing speaker_id_map for %d speakers", fn, mc.NumSpeakers)
+	}
+	return mc, nil
+}
+
+// Piper is the Synthesizer for a Piper model, or one of the speakers in a multi-speaker model
+type Piper struct {
+	ExeFn    string
+	ModelFn  string
+	ConfigFn string
+
+	// Speaker is the default speaker; it's empty for single-speaker models
+	Speaker   string
+	SpeakerID int
+
+	speakers map[string]int
+}
+
+// speakerID returns the ID of the speaker to use with params p
+func (ps *Piper) speakerID(p config.TTSParams) (id int, ok bool, err error) {
+	if p.Speaker == "" {
+		return ps.SpeakerID, ps.Speaker != "", nil
+	}
+	if id, ok := ps.speakers[p.Speaker]; ok {
+		return id, true, nil
+	}
+	id, err = strconv.Atoi(p.Speaker)
+	if err != nil || id < 0 || id >= max(len(ps.speakers), 1) {
+		return 0, false, fmt.Errorf("Unknown speaker `%s`", p.Speaker)
+	}
+	return id, true, nil
+}
+
+// args returns the piper args to synthesize text with params p
+//
+// If outputFn is empty, the wav file is written to stdout.
+func (ps *Piper) args(p config.TTSParams, outputFn string) ([]string, error) {
+	if outputFn == "" {
+		outputFn = "-"
+	}
+	args := []string{
+		"--model", ps.ModelFn,
+		"--config", ps.ConfigFn,
+		"--output_file", outputFn,
+	}
+	p = p.Clamp()
+	if p.Rate > 0 {
+		args = append(args, "--length_scale", strconv.FormatFloat(1/p.Rate, 'f', 3, 64))
+	}
+	if p.Variability > 0 {
+		args = append(args, "--noise_scale", strconv.FormatFloat(p.Variability, 'f', 3, 64))
+	}
+	if p.Cadence > 0 {
+		args = append(args, "--noise_w", strconv.FormatFloat(p.Cadence, 'f', 3, 64))
+	}
+	id, ok, err := ps.speakerID(p)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		args = append(args, "--speaker", strconv.Itoa(id))
+	}
+	return args, nil
+}
+
+func (ps *Piper) Synthesize(ctx context.Context, text string, p config.TTSParams) ([]byte, error) {
+	// validate the params before starting the process
+	if _, err := ps.args(p, ""); err != nil {
+		return nil, fmt.Errorf("Piper.Synthesize: %w", err)
+	}
+	wav, err := runTTS(ctx, ps.ExeFn, func(outputFn string) []string {
+		args, _ := ps.args(p, outputFn)
+		return args
+	}, text)
+	if err != nil {
+		return nil, fmt.Errorf("Piper.Synthesize: %w", err)
+	}
+	return wav, nil
+}
+
+// PiperDir returns the directory the piper binary and embedded voices are installed in
+func PiperDir() (string, error) {
+	dir, err := xdg.DataFile("ab-piper")
+	if err != nil {
+		return "", fmt.Errorf("PiperDir: %w", err)
+	}
+	return dir, nil
+}
+
+// piper.New doesn't return the paths it installs to, so piperExeFn and piperVoiceFns follow its layout:
+// each asset is installed in a dir named after it, like piper.New does.
+
+// piperExeFn returns the path of the piper binary installed by piper.New in piperDir
+func piperExeFn(piperDir string) string {
+	return filepath.Join(piperDir, "piper-bin-"+piperBinAsset.Name, piperExe)
+}
+
+// piperVoiceFns returns the paths of the model and config of the voice a installed by piper.New in piperDir
+func piperVoiceFns(piperDir string, a asset.Asset) (modelFn, configFn string) {
+	dir := filepath.Join(piperDir, "piper-voice-"+a.Name)
+	return filepath.Join(dir, "voice.onnx"), filepath.Join(dir, "voice.json")
+}
+
+// LoadModel loads the model modelFn, and its config configFn
+//
+// Multi-speaker models return a voice for each speaker, named `<name>:<speaker>`.
+func LoadModel(name, modelFn, configFn, exeFn string) ([]*Voice, error) {
+	fi, err := os.Stat(modelFn)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("LoadModel(%s): %w", name, err)
+	case fi.Size() == 0:
+		return nil, fmt.Errorf("LoadModel(%s): `%s` is empty", name, modelFn)
+	}
+
+	mc, err := ReadModelConfig(configFn)
+	if err != nil {
+		return nil, fmt.Errorf("LoadModel(%s): %w", name, err)
+	}
+
+	ps := Piper{
+		ExeFn:    exeFn,
+		ModelFn:  modelFn,
+		ConfigFn: configFn,
+		speakers: mc.SpeakerIDMap,
+	}
+	base := Voice{
+		Name:       name,
+		Backend:    config.PiperBackend,
+		Model:      name,
+		Language:   cmpOr(mc.Language.Code, mc.Espeak.Voice),
+		Quality:    mc.Audio.Quality,
+		SampleRate: mc.Audio.SampleRate,
+	}
+	if len(mc.SpeakerIDMap) <= 1 {
+		base.synth = &ps
+		return []*Voice{&base}, nil
+	}
+
+	l := make([]*Voice, 0, len(mc.SpeakerIDMap))
+	for spk, id := range mc.SpeakerIDMap {
+		v, ps := base, ps
+		v.Name = name + ":" + spk
+		v.Speaker = spk
+		v.SpeakerID = id
+		ps.Speaker = spk
+		ps.SpeakerID = id
+		v.synth = &ps
+		l = append(l, &v)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].SpeakerID < l[j].SpeakerID })
+	return l, nil
+}
+
+// EmbeddedVoice installs the embedded voice a into piperDir, and loads it
+func EmbeddedVoice(piperDir string, a asset.Asset) ([]*Voice, error) {
+	if _, err := piper.New(piperDir, a); err != nil {
+		return nil, fmt.Errorf("EmbeddedVoice(%s): %w", a.Name, err)
+	}
+	exeFn := piperExeFn(piperDir)
+	if _, err := os.Stat(exeFn); err != nil {
+		return nil, fmt.Errorf("EmbeddedVoice(%s): piper wasn't installed where expected: %w", a.Name, err)
+	}
+	modelFn, configFn := piperVoiceFns(piperDir, a)
+	l, err := LoadModel(a.Name, modelFn, configFn, exeFn)
+	if err != nil {
+		return nil, fmt.Errorf("EmbeddedVoice: %w", err)
+	}
+	for _, v := range l {
+		v.Embedded = true
+	}
+	return l, nil
+}
+
+// LoadVoices loads the models in dir
+//
+// Each model is a .onnx file, with its config in a .onnx.json file of the same name.
+// Models that fail to load are skipped, and their errors returned.
+// A missing dir is not an error.
+func LoadVoices(dir, piperDir string) ([]*Voice, []error) {
+	des, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("LoadVoices: %w", err)}
+	}
+
+	var voices []*Voice
+	var errs []error
+	for _, de := range des {
+		nm := de.Name()
+		if de.IsDir() || !strings.HasSuffix(nm, ModelExt) {
+			continue
+		}
+		name := strings.TrimSuffix(nm, ModelExt)
+		l, err := LoadModel(name, filepath.Join(dir, nm), filepath.Join(dir, name+ModelConfigExt), piperExeFn(piperDir))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		voices = append(voices, l...)
+	}
+	return voices, errs
+}
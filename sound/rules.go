@@ -0,0 +1,58 @@
+package sound
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/amitybell/srcvox/translate"
+)
+
+type RuleIssue struct {
+	Key     string `json:"key"`
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+type RulesInfo struct {
+	Rules  translate.Rules `json:"rules"`
+	Issues []RuleIssue     `json:"issues"`
+}
+
+// CheckRules validates the substitutes in r against the sound catalog
+//
+// Substitutes that look like a sound name (a single word), but don't name a sound are reported.
+// They are still usable, but will be spoken instead of played.
+func CheckRules(r translate.Rules) []RuleIssue {
+	var issues []RuleIssue
+	for key, targets := range r.Expand().Substitutes {
+		for _, target := range targets {
+			if HasSound(target) || strings.ContainsFunc(target, isPhrase) {
+				continue
+			}
+			issues = append(issues, RuleIssue{
+				Key:     key,
+				Target:  target,
+				Message: fmt.Sprintf("There is no sound named `%s`; it will be spoken instead", target),
+			})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Key != issues[j].Key {
+			return issues[i].Key < issues[j].Key
+		}
+		return issues[i].Target < issues[j].Target
+	})
+	return issues
+}
+
+func isPhrase(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) && r != '-' && r != '_'
+}
+
+// ActiveRulesInfo returns the active rules and their issues
+func ActiveRulesInfo() RulesInfo {
+	r := translate.ActiveRules()
+	return RulesInfo{Rules: r, Issues: CheckRules(r)}
+}
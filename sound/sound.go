@@ -1,37 +1,55 @@
 package sound
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"path"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/amitybell/memio"
-	"github.com/amitybell/piper"
 	"github.com/amitybell/srcvox/audio"
 	"github.com/amitybell/srcvox/config"
 	"github.com/amitybell/srcvox/files"
 	"github.com/amitybell/srcvox/translate"
+	"github.com/gopxl/beep"
 )
 
 var (
 	ErrEmptyMessage = errors.New("Empty message")
-)
 
-var SoundsList, SoundsMap = func() ([]SoundInfo, map[string]SoundInfo) {
-	m := map[string]SoundInfo{}
+	bleepFormat = beep.Format{
+		Precision:   2,
+		NumChannels: 1,
+		SampleRate:  22050,
+	}
+)
 
+var soundFiles = func() map[string]bool {
+	m := map[string]bool{}
 	fis, _ := fs.ReadDir(files.Sounds, "sounds")
 	for _, fi := range fis {
 		fn := fi.Name()
-		nm := fn[:len(fn)-len(path.Ext(fn))]
-		m[nm] = SoundInfo{
-			Name: nm,
-		}
+		m[fn[:len(fn)-len(path.Ext(fn))]] = true
 	}
+	return m
+}()
 
-	for nm, _ := range translate.Substites {
+// HasSound reports whether there's a sound file named name
+func HasSound(name string) bool {
+	return soundFiles[name]
+}
+
+// Sounds returns the list of sound files and words that have substitutes in the active rules
+func Sounds() []SoundInfo {
+	m := map[string]SoundInfo{}
+	for nm := range soundFiles {
+		m[nm] = SoundInfo{Name: nm}
+	}
+	for _, nm := range translate.ActiveRules().SubstituteNames() {
 		m[nm] = SoundInfo{Name: nm}
 	}
 
@@ -40,8 +58,8 @@ var SoundsList, SoundsMap = func() ([]SoundInfo, map[string]SoundInfo) {
 		l = append(l, si)
 	}
 	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
-	return l, m
-}()
+	return l
+}
 
 type SoundInfo struct {
 	Name string `json:"name"`
@@ -64,30 +82,109 @@ func LoadSound(name string) (*audio.Audio, error) {
 	return audio.Read(name, f)
 }
 
-func SoundOrTTS(tts *piper.TTS, cfg config.Config, username, text string) (au *audio.Audio, err error) {
-	if n := cfg.TextLimit; n > 0 && len(text) > n {
-		text = text[:n]
+// findSound loads the sound named name, falling back to its lower-case name
+func findSound(name string) (*audio.Audio, error) {
+	au, err := LoadSound(name)
+	if err != nil && strings.ToLower(name) != name {
+		if au, err := LoadSound(strings.ToLower(name)); err == nil {
+			return au, nil
+		}
 	}
+	return au, err
+}
 
-	_, name := translate.ClanName(username)
-	if name == "" {
-		name = username
+func Bleep() (*audio.Audio, error) {
+	return audio.Tone("bleep", 1000, 400*time.Millisecond, bleepFormat)
+}
+
+func synthesize(ctx context.Context, voice *Voice, params config.TTSParams, txt string) (*audio.Audio, error) {
+	if voice == nil {
+		return nil, fmt.Errorf("synthesize: %w", ErrNoVoice)
 	}
+	k := synthCacheKey(voice, params, txt)
+	wav, ok := synthCache.get(k)
+	if !ok {
+		var err error
+		wav, err = voice.Synthesize(ctx, txt, params)
+		if err != nil {
+			return nil, err
+		}
+		synthCache.put(k, wav)
+	}
+	au, err := audio.Read(txt, memio.NewFile(wav))
+	if err != nil {
+		return nil, fmt.Errorf("audio.Read: %w", err)
+	}
+	au.TTS = true
+	return au, nil
+}
 
-	txt := translate.Translate(name, text)
-	if txt == "" {
-		return nil, fmt.Errorf("SoundOrTTS(`%s`): %w", text, ErrEmptyMessage)
+func isSound(name string) bool {
+	return HasSound(name) || HasSound(strings.ToLower(name))
+}
+
+// Explain runs the configured pipeline on text, without synthesizing it
+func Explain(cfg config.Config, filter *translate.Filter, vars translate.Vars, text string) (translate.Trace, error) {
+	if n := cfg.TextLimit; n > 0 && len(text) > n {
+		text = text[:n]
+	}
+	p, err := translate.NewPipeline(cfg.Pipeline)
+	if err != nil {
+		return translate.Trace{}, fmt.Errorf("Explain: %w", err)
 	}
-	if au, err := LoadSound(txt); err == nil {
-		return au, nil
+	env := translate.Env{
+		Vars:      vars,
+		Filter:    filter,
+		Normalize: cfg.Normalize,
+		IsSound:   isSound,
 	}
-	wav, err := tts.Synthesize(txt)
+	return p.Run(env, text), nil
+}
+
+// SoundOrTTS returns the audio of text said with tts
+//
+// Chat markup is spoken with the voice params and volume of its style.
+// Synthesis is cancelled if ctx is cancelled.
+func SoundOrTTS(ctx context.Context, tts TTS, cfg config.Config, filter *translate.Filter, vars translate.Vars, text string) (au *audio.Audio, err error) {
+	tr, err := Explain(cfg, filter, vars, text)
 	if err != nil {
-		return nil, fmt.Errorf("SoundOrTTS(`%s`): Synthesize: %w", text, err)
+		return nil, fmt.Errorf("SoundOrTTS(`%s`): %w", text, err)
+	}
+	if tr.Output.Dropped {
+		return nil, fmt.Errorf("SoundOrTTS(`%s`): %w", text, translate.ErrFiltered)
+	}
+
+	var parts []*audio.Audio
+	for _, seg := range tr.Output.Segments {
+		var au *audio.Audio
+		var err error
+		switch {
+		case seg.Bleep:
+			au, err = Bleep()
+		case seg.Sound:
+			au, err = findSound(seg.Text)
+		default:
+			voice, params, vol := tts.styled(seg.Style)
+			au, err = synthesize(ctx, voice, params, seg.Text)
+			if err == nil && vol != 0 {
+				au, err = audio.Volume(au, vol)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SoundOrTTS(`%s`): %w", text, err)
+		}
+		parts = append(parts, au)
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil, fmt.Errorf("SoundOrTTS(`%s`): %w", text, ErrEmptyMessage)
+	case 1:
+		return parts[0], nil
 	}
-	au, err = audio.Read(txt, memio.NewFile(wav))
+	au, err = audio.Concat(text, parts[0].Format, parts...)
 	if err != nil {
-		return nil, fmt.Errorf("SoundOrTTS(`%s`): audio.Read: %w", text, err)
+		return nil, fmt.Errorf("SoundOrTTS(`%s`): %w", text, err)
 	}
 	au.TTS = true
 	return au, nil
@@ -0,0 +1,89 @@
+package sound
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/translate"
+)
+
+func TestSoundOrTTS(t *testing.T) {
+	fs := &FakeSynthesizer{}
+	voice := NewVoice("TestSoundOrTTS", fs)
+	params := config.TTSParams{Rate: 1.5}
+	vars := translate.Vars{"name": "bob"}
+
+	au, err := SoundOrTTS(context.Background(), TTS{Voice: voice, Params: params}, config.Config{}, nil, vars, "hello $name")
+	if err != nil {
+		t.Fatalf("SoundOrTTS: %s", err)
+	}
+	if !au.TTS || au.Dur <= 0 {
+		t.Fatalf("SoundOrTTS: Expected TTS audio; Got %+v", au)
+	}
+	texts, ps := fs.Calls()
+	if !slices.Equal(texts, []string{"hello bob"}) || ps[0] != params {
+		t.Fatalf("SoundOrTTS: Expected one call with `hello bob`; Got %q, %+v", texts, ps)
+	}
+
+	// synthesized speech is cached
+	if _, err := SoundOrTTS(context.Background(), TTS{Voice: voice, Params: params}, config.Config{}, nil, vars, "hello $name"); err != nil {
+		t.Fatalf("SoundOrTTS: %s", err)
+	}
+	if texts, _ := fs.Calls(); len(texts) != 1 {
+		t.Fatalf("SoundOrTTS: Expected a cache hit; Got calls %q", texts)
+	}
+
+	// but not for different params
+	if _, err := SoundOrTTS(context.Background(), TTS{Voice: voice, Params: config.TTSParams{Rate: 2}}, config.Config{}, nil, vars, "hello $name"); err != nil {
+		t.Fatalf("SoundOrTTS: %s", err)
+	}
+	if texts, _ := fs.Calls(); len(texts) != 2 {
+		t.Fatalf("SoundOrTTS: Expected a cache miss; Got calls %q", texts)
+	}
+}
+
+func TestSoundOrTTSError(t *testing.T) {
+	errFake := errors.New("fake")
+	voice := NewVoice("TestSoundOrTTSError", &FakeSynthesizer{Err: errFake})
+	if _, err := SoundOrTTS(context.Background(), TTS{Voice: voice}, config.Config{}, nil, nil, "say something"); !errors.Is(err, errFake) {
+		t.Fatalf("SoundOrTTS: Expected %v; Got %v", errFake, err)
+	}
+	if _, err := SoundOrTTS(context.Background(), TTS{}, config.Config{}, nil, nil, "say something"); !errors.Is(err, ErrNoVoice) {
+		t.Fatalf("SoundOrTTS: Expected %v; Got %v", ErrNoVoice, err)
+	}
+}
+
+func TestSoundOrTTSMarkup(t *testing.T) {
+	main, other := &FakeSynthesizer{}, &FakeSynthesizer{}
+	otherParams := config.TTSParams{Rate: 2}
+	tts := TTS{
+		Voice: NewVoice("TestSoundOrTTSMarkup", main),
+		FindVoice: func(name string) (*Voice, config.TTSParams, bool) {
+			if name != "other" {
+				return nil, config.TTSParams{}, false
+			}
+			return NewVoice("TestSoundOrTTSMarkup:other", other), otherParams, true
+		},
+	}
+
+	if _, err := SoundOrTTS(context.Background(), tts, config.Config{}, nil, nil, "*amazing* ~quiet~ [voice=nobody] plain [voice=other] switched"); err != nil {
+		t.Fatalf("SoundOrTTS: %s", err)
+	}
+
+	texts, ps := main.Calls()
+	expTexts := []string{"amazing", "quiet", "plain"}
+	expParams := []config.TTSParams{
+		{Rate: emphasisRate, Variability: emphasisVariability},
+		{Rate: slowRate},
+		{},
+	}
+	if !slices.Equal(texts, expTexts) || !slices.Equal(ps, expParams) {
+		t.Fatalf("SoundOrTTS: Expected %q with %+v; Got %q with %+v", expTexts, expParams, texts, ps)
+	}
+	if texts, ps := other.Calls(); !slices.Equal(texts, []string{"switched"}) || ps[0] != otherParams {
+		t.Fatalf("SoundOrTTS: Expected the switched voice to say `switched`; Got %q with %+v", texts, ps)
+	}
+}
@@ -0,0 +1,56 @@
+package sound
+
+import (
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/translate"
+)
+
+const (
+	// emphasisRate and emphasisVariability are applied to *emphasis*
+	emphasisRate        = 0.9
+	emphasisVariability = 0.9
+	emphasisVolume      = 0.5
+
+	// slowRate is applied to ~slow~ text, which is also quieter, like a whisper
+	slowRate   = 0.7
+	slowVolume = -1
+)
+
+// TTS is the voice a message is spoken with
+type TTS struct {
+	Voice  *Voice
+	Params config.TTSParams
+
+	// FindVoice returns the voice named name, and its params, for [voice=name] markup
+	// If it's nil, or the voice isn't found, the markup is ignored.
+	FindVoice func(name string) (*Voice, config.TTSParams, bool)
+}
+
+// styled returns the voice and params used to speak text with style st,
+// and the change in volume
+func (tts TTS) styled(st translate.Style) (voice *Voice, p config.TTSParams, vol float64) {
+	voice, p = tts.Voice, tts.Params
+	if st.Voice != "" && tts.FindVoice != nil {
+		if v, vp, ok := tts.FindVoice(st.Voice); ok {
+			voice, p = v, vp
+		}
+	}
+
+	rate := p.Rate
+	if rate <= 0 {
+		rate = 1
+	}
+	if st.Emphasis {
+		rate *= emphasisRate
+		p.Variability = max(p.Variability, emphasisVariability)
+		vol += emphasisVolume
+	}
+	if st.Slow {
+		rate *= slowRate
+		vol += slowVolume
+	}
+	if rate != 1 {
+		p.Rate = rate
+	}
+	return voice, p, vol
+}
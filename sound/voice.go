@@ -0,0 +1,170 @@
+package sound
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+var (
+	ErrNoVoice = errors.New("No voice")
+)
+
+// Synthesizer is a speech engine
+type Synthesizer interface {
+	// Synthesize returns a wav file of text spoken with the params p
+	Synthesize(ctx context.Context, text string, p config.TTSParams) (wav []byte, err error)
+}
+
+// Voice is a named voice, and the Synthesizer used to speak with it
+type Voice struct {
+	Name       string `json:"name"`
+	Backend    string `json:"backend"`
+	Model      string `json:"model"`
+	Speaker    string `json:"speaker"`
+	SpeakerID  int    `json:"speakerID"`
+	Language   string `json:"language"`
+	Quality    string `json:"quality"`
+	SampleRate int    `json:"sampleRate"`
+	Embedded   bool   `json:"embedded"`
+
+	synth Synthesizer
+}
+
+// NewVoice returns a voice named name, that speaks with synth
+func NewVoice(name string, synth Synthesizer) *Voice {
+	return &Voice{Name: name, Model: name, synth: synth}
+}
+
+// Synthesize returns a wav file of v speaking text, with the params p
+func (v *Voice) Synthesize(ctx context.Context, text string, p config.TTSParams) (wav []byte, err error) {
+	if v == nil || v.synth == nil {
+		return nil, fmt.Errorf("Voice.Synthesize: %w", ErrNoVoice)
+	}
+	wav, err = v.synth.Synthesize(ctx, text, p)
+	if err != nil {
+		return nil, fmt.Errorf("Voice.Synthesize(%s): %w", v.Name, err)
+	}
+	return wav, nil
+}
+
+// runTTS runs the command exe, with text as its input, and returns the wav file it writes
+//
+// The command is killed if ctx is cancelled.
+// args returns the command's arguments, given the file it should write to;
+// an empty file name means stdout.
+func runTTS(ctx context.Context, exe string, args func(outputFn string) []string, text string) ([]byte, error) {
+	// some engines can't write wav files to stdout on windows
+	outputFn := ""
+	var stdout *bytes.Buffer
+	if runtime.GOOS != "windows" {
+		stdout = bytes.NewBuffer(nil)
+	} else {
+		tmpDir, err := os.MkdirTemp("", "srcvox-tts.")
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create temp file: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		outputFn = filepath.Join(tmpDir, "tts.wav")
+	}
+
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, exe, args(outputFn)...)
+	if filepath.IsAbs(exe) {
+		cmd.Dir = filepath.Dir(exe)
+	}
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = sysProcAttr
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %s: %s", cmd, err, stderr.Bytes())
+	}
+
+	if stdout != nil {
+		return stdout.Bytes(), nil
+	}
+	return os.ReadFile(outputFn)
+}
+
+// ConfigVoice returns the voices configured by c
+//
+// Multi-speaker piper models return a voice for each speaker, see LoadModel.
+func ConfigVoice(name string, c config.TTSVoice, piperDir string) ([]*Voice, error) {
+	switch c.Backend {
+	case config.PiperBackend, "":
+		if c.Model == "" {
+			return nil, fmt.Errorf("ConfigVoice(%s): The piper backend requires a model", name)
+		}
+		exe := c.Exe
+		if exe == "" {
+			exe = piperExeFn(piperDir)
+		}
+		l, err := LoadModel(name, c.Model, strings.TrimSuffix(c.Model, ModelExt)+ModelConfigExt, exe)
+		if err != nil {
+			return nil, fmt.Errorf("ConfigVoice: %w", err)
+		}
+		for _, v := range l {
+			v.Language = cmpOr(c.Language, v.Language)
+		}
+		return l, nil
+	case config.EspeakBackend:
+		v := NewVoice(name, &Espeak{Exe: c.Exe, Voice: c.Voice})
+		v.Backend = config.EspeakBackend
+		v.Model = cmpOr(c.Voice, defaultEspeakVoice)
+		v.Language = cmpOr(c.Language, v.Model)
+		return []*Voice{v}, nil
+	case config.HTTPBackend:
+		if c.URL == "" {
+			return nil, fmt.Errorf("ConfigVoice(%s): The http backend requires a url", name)
+		}
+		v := NewVoice(name, &HTTPSynthesizer{URL: c.URL, Voice: c.Voice, Timeout: c.Timeout.D})
+		v.Backend = config.HTTPBackend
+		v.Model = cmpOr(c.Voice, name)
+		v.Language = c.Language
+		return []*Voice{v}, nil
+	default:
+		return nil, fmt.Errorf("ConfigVoice(%s): Unknown backend `%s`", name, c.Backend)
+	}
+}
+
+// ConfigVoices returns the voices configured in m, sorted by name
+//
+// Voices that fail to load are skipped, and their errors returned.
+func ConfigVoices(m map[string]config.TTSVoice, piperDir string) ([]*Voice, []error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var voices []*Voice
+	var errs []error
+	for _, name := range names {
+		l, err := ConfigVoice(name, m[name], piperDir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		voices = append(voices, l...)
+	}
+	return voices, errs
+}
+
+func cmpOr(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,215 @@
+package sound
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/amitybell/piper"
+	asset "github.com/amitybell/piper-asset"
+	"github.com/amitybell/srcvox/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+func writeModel(t *testing.T, dir, name, cfg string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+ModelExt), []byte("onnx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg == "" {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+ModelConfigExt), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadVoices(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "en_GB-single-medium", `{
+		"audio": {"sample_rate": 22050, "quality": "medium"},
+		"language": {"code": "en_GB"},
+		"num_speakers": 1,
+		"phoneme_id_map": {"a": [1]}
+	}`)
+	writeModel(t, dir, "en_US-multi-low", `{
+		"audio": {"sample_rate": 16000, "quality": "low"},
+		"espeak": {"voice": "en-us"},
+		"num_speakers": 2,
+		"speaker_id_map": {"p1": 1, "p0": 0},
+		"phoneme_id_map": {"a": [1]}
+	}`)
+	writeModel(t, dir, "no-config", "")
+	writeModel(t, dir, "bad-rate", `{"audio": {"sample_rate": 0}, "phoneme_id_map": {"a": [1]}}`)
+
+	voices, errs := LoadVoices(dir, t.TempDir())
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors; Got %d: %v", len(errs), errs)
+	}
+
+	exp := []Voice{
+		{Name: "en_GB-single-medium", Backend: "piper", Model: "en_GB-single-medium", Language: "en_GB", Quality: "medium", SampleRate: 22050},
+		{Name: "en_US-multi-low:p0", Backend: "piper", Model: "en_US-multi-low", Speaker: "p0", SpeakerID: 0, Language: "en-us", Quality: "low", SampleRate: 16000},
+		{Name: "en_US-multi-low:p1", Backend: "piper", Model: "en_US-multi-low", Speaker: "p1", SpeakerID: 1, Language: "en-us", Quality: "low", SampleRate: 16000},
+	}
+	if len(voices) != len(exp) {
+		t.Fatalf("Expected %d voices; Got %d: %v", len(exp), len(voices), voices)
+	}
+	for i, v := range voices {
+		v := *v
+		v.synth = nil
+		if !reflect.DeepEqual(v, exp[i]) {
+			t.Fatalf("Voice %d: Expected %+v; Got %+v", i, exp[i], v)
+		}
+	}
+}
+
+func TestLoadVoicesMissingDir(t *testing.T) {
+	voices, errs := LoadVoices(filepath.Join(t.TempDir(), "voices"), "")
+	if len(voices) != 0 || len(errs) != 0 {
+		t.Fatalf("Expected no voices or errors; Got %v, %v", voices, errs)
+	}
+}
+
+func TestPiperArgs(t *testing.T) {
+	ps := &Piper{ModelFn: "m", ConfigFn: "c", Speaker: "p1", SpeakerID: 1, speakers: map[string]int{"p0": 0, "p1": 1}}
+	cases := []struct {
+		p   config.TTSParams
+		exp []string
+	}{
+		{config.TTSParams{}, []string{"--speaker", "1"}},
+		{config.TTSParams{Rate: 2, Variability: 0.5, Cadence: 0.8}, []string{"--length_scale", "0.500", "--noise_scale", "0.500", "--noise_w", "0.800", "--speaker", "1"}},
+		{config.TTSParams{Rate: 100, Speaker: "p0"}, []string{"--length_scale", "0.250", "--speaker", "0"}},
+		{config.TTSParams{Speaker: "1"}, []string{"--speaker", "1"}},
+	}
+	for _, c := range cases {
+		args, err := ps.args(c.p, "")
+		if err != nil {
+			t.Fatalf("Piper.args(%+v): %s", c.p, err)
+		}
+		exp := append([]string{"--model", "m", "--config", "c", "--output_file", "-"}, c.exp...)
+		if !slices.Equal(args, exp) {
+			t.Fatalf("Piper.args(%+v): Expected %q; Got %q", c.p, exp, args)
+		}
+	}
+
+	if _, err := ps.args(config.TTSParams{Speaker: "p2"}, ""); err == nil {
+		t.Fatalf("Piper.args: Expected an error for an unknown speaker")
+	}
+}
+
+func TestEspeakArgs(t *testing.T) {
+	es := &Espeak{Voice: "en-us"}
+	cases := []struct {
+		p   config.TTSParams
+		out string
+		exp []string
+	}{
+		{config.TTSParams{}, "", []string{"-v", "en-us", "--stdout"}},
+		{config.TTSParams{Rate: 2, Variability: 1.5, Speaker: "f3"}, "out.wav", []string{"-v", "en-us+f3", "-s", "350", "-p", "75", "-w", "out.wav"}},
+	}
+	for _, c := range cases {
+		if args := es.args(c.p, c.out); !slices.Equal(args, c.exp) {
+			t.Fatalf("Espeak.args(%+v): Expected %q; Got %q", c.p, c.exp, args)
+		}
+	}
+}
+
+func TestConfigVoices(t *testing.T) {
+	m := map[string]config.TTSVoice{
+		"robot":  {Backend: config.EspeakBackend, Voice: "en-us"},
+		"server": {Backend: config.HTTPBackend, URL: "http://127.0.0.1:5002/tts", Voice: "p225", Language: "en"},
+		"nourl":  {Backend: config.HTTPBackend},
+		"what":   {Backend: "what"},
+	}
+	voices, errs := ConfigVoices(m, "")
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors; Got %d: %v", len(errs), errs)
+	}
+	if len(voices) != 2 || voices[0].Name != "robot" || voices[1].Name != "server" {
+		t.Fatalf("Expected voices robot and server; Got %+v", voices)
+	}
+	if v := voices[0]; v.Backend != config.EspeakBackend || v.Language != "en-us" {
+		t.Fatalf("Unexpected espeak voice %+v", v)
+	}
+	if v := voices[1]; v.Backend != config.HTTPBackend || v.Model != "p225" || v.Language != "en" {
+		t.Fatalf("Unexpected http voice %+v", v)
+	}
+}
+
+func TestSynthCacheKey(t *testing.T) {
+	v := &Voice{Name: "jenny"}
+	a := synthCacheKey(v, config.TTSParams{}, "hi")
+	keys := []string{
+		synthCacheKey(v, config.TTSParams{Rate: 1.5}, "hi"),
+		synthCacheKey(v, config.TTSParams{Variability: 0.3}, "hi"),
+		synthCacheKey(v, config.TTSParams{Cadence: 0.3}, "hi"),
+		synthCacheKey(v, config.TTSParams{Speaker: "p0"}, "hi"),
+		synthCacheKey(&Voice{Name: "alan"}, config.TTSParams{}, "hi"),
+	}
+	for _, k := range keys {
+		if k == a {
+			t.Fatalf("Expected cache key %q to differ from %q", k, a)
+		}
+	}
+}
+
+// fakeVoiceAsset returns a voice asset in the format installed by piper.New
+func fakeVoiceAsset(t *testing.T, name string) asset.Asset {
+	t.Helper()
+	files := map[string]string{
+		"MODEL_CARD": name,
+		"voice.onnx": "onnx",
+		"voice.json": `{"audio": {"sample_rate": 22050, "quality": "medium"}, "language": {"code": "en_GB"}, "phoneme_id_map": {"a": [1]}}`,
+	}
+	buf := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(zw)
+	for nm, s := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: nm, Mode: 0o644, Size: int64(len(s)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return asset.Asset{Name: name, FS: fstest.MapFS{
+		piper.DistArcName:  {Data: buf.Bytes()},
+		piper.DistMetaName: {Data: []byte(`{"name": "` + name + `"}`)},
+	}}
+}
+
+func TestEmbeddedVoice(t *testing.T) {
+	piperDir := t.TempDir()
+	l, err := EmbeddedVoice(piperDir, fakeVoiceAsset(t, "fake"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0].Name != "fake" || !l[0].Embedded || l[0].Language != "en_GB" {
+		t.Fatalf("Expected the embedded voice; Got %+v", l)
+	}
+	ps, ok := l[0].synth.(*Piper)
+	if !ok {
+		t.Fatalf("Expected a piper voice; Got %T", l[0].synth)
+	}
+	// the paths must be where piper.New installed the binary and voice
+	for _, fn := range []string{ps.ExeFn, ps.ModelFn, ps.ConfigFn} {
+		if _, err := os.Stat(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
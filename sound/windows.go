@@ -0,0 +1,22 @@
+//go:build windows
+
+package sound
+
+import (
+	"syscall"
+
+	windows "github.com/amitybell/piper-bin-windows"
+)
+
+var (
+	// piperBinAsset is the piper binary installed by piper.New
+	piperBinAsset = windows.Asset
+
+	piperExe  = "piper.exe"
+	espeakExe = "espeak-ng.exe"
+
+	sysProcAttr = &syscall.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: 0x08000000,
+	}
+)
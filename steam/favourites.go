@@ -0,0 +1,352 @@
+package steam
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+const (
+	favouritesKey = "/servers/favourites"
+	historyKey    = "/servers/history"
+
+	// maxHistory is the number of servers kept in the join history
+	maxHistory = 200
+)
+
+var (
+	ErrNotFavourite = errors.New("Not a favourite")
+)
+
+// Favourite is a server saved by the user
+type Favourite struct {
+	Addr   string   `json:"addr"`
+	Name   string   `json:"name"`
+	GameID ID       `json:"gameID"`
+	Tags   []string `json:"tags"`
+	Note   string   `json:"note"`
+	// Added is when the server was added to the favourites
+	Added      time.Time `json:"added"`
+	LastPlayed time.Time `json:"lastPlayed"`
+}
+
+// HistoryEntry is a server the user joined
+type HistoryEntry struct {
+	Addr       string    `json:"addr"`
+	Name       string    `json:"name"`
+	GameID     ID        `json:"gameID"`
+	LastPlayed time.Time `json:"lastPlayed"`
+	// Joins is the number of times the server was joined
+	Joins int `json:"joins"`
+}
+
+// ServerBook stores the user's favourite servers and join history
+type ServerBook struct {
+	db *store.DB
+	mu sync.Mutex
+}
+
+func NewServerBook(db *store.DB) *ServerBook {
+	return &ServerBook{db: db}
+}
+
+// getOrEmpty is like store.Get, but a missing key isn't an error
+func getOrEmpty[T any](db *store.DB, k string) (T, error) {
+	v, err := store.Get[T](db, k)
+	if errors.Is(err, store.ErrNotFound) {
+		return v, nil
+	}
+	return v, err
+}
+
+func (sb *ServerBook) favourites() (map[string]Favourite, error) {
+	m, err := getOrEmpty[map[string]Favourite](sb.db, favouritesKey)
+	if err != nil {
+		return nil, fmt.Errorf("ServerBook.favourites: %w", err)
+	}
+	if m == nil {
+		m = map[string]Favourite{}
+	}
+	return m, nil
+}
+
+func (sb *ServerBook) history() ([]HistoryEntry, error) {
+	l, err := getOrEmpty[[]HistoryEntry](sb.db, historyKey)
+	if err != nil {
+		return nil, fmt.Errorf("ServerBook.history: %w", err)
+	}
+	return l, nil
+}
+
+// Favourites returns the favourite servers, ordered by name
+func (sb *ServerBook) Favourites() ([]Favourite, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	m, err := sb.favourites()
+	if err != nil {
+		return nil, err
+	}
+	l := make([]Favourite, 0, len(m))
+	for _, f := range m {
+		l = append(l, f)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Name != l[j].Name {
+			return l[i].Name < l[j].Name
+		}
+		return l[i].Addr < l[j].Addr
+	})
+	return l, nil
+}
+
+// updateFavourites calls f with the favourites, and saves them if it doesn't return an error
+func (sb *ServerBook) updateFavourites(f func(m map[string]Favourite) error) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	m, err := sb.favourites()
+	if err != nil {
+		return err
+	}
+	if err := f(m); err != nil {
+		return err
+	}
+	return store.Put(sb.db, favouritesKey, m)
+}
+
+// AddFavourite adds the server at addr to the favourites
+//
+// If it's already a favourite, its name is updated.
+func (sb *ServerBook) AddFavourite(addr, name string, gameID ID) error {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return fmt.Errorf("AddFavourite: Invalid address")
+	}
+	return sb.updateFavourites(func(m map[string]Favourite) error {
+		f, ok := m[addr]
+		if !ok {
+			f = Favourite{Addr: addr, Added: time.Now().UTC()}
+		}
+		if name != "" {
+			f.Name = name
+		}
+		if gameID != 0 {
+			f.GameID = gameID
+		}
+		m[addr] = f
+		return nil
+	})
+}
+
+// RemoveFavourite removes the server at addr from the favourites
+func (sb *ServerBook) RemoveFavourite(addr string) error {
+	return sb.updateFavourites(func(m map[string]Favourite) error {
+		if _, ok := m[addr]; !ok {
+			return fmt.Errorf("RemoveFavourite(%s): %w", addr, ErrNotFavourite)
+		}
+		delete(m, addr)
+		return nil
+	})
+}
+
+// SetFavouriteTags sets the tags of the favourite server at addr
+func (sb *ServerBook) SetFavouriteTags(addr string, tags []string) error {
+	return sb.updateFavourites(func(m map[string]Favourite) error {
+		f, ok := m[addr]
+		if !ok {
+			return fmt.Errorf("SetFavouriteTags(%s): %w", addr, ErrNotFavourite)
+		}
+		f.Tags = nil
+		seen := map[string]bool{}
+		for _, s := range tags {
+			s = strings.TrimSpace(s)
+			if s != "" && !seen[s] {
+				seen[s] = true
+				f.Tags = append(f.Tags, s)
+			}
+		}
+		m[addr] = f
+		return nil
+	})
+}
+
+// SetFavouriteNote sets the note of the favourite server at addr
+func (sb *ServerBook) SetFavouriteNote(addr string, note string) error {
+	return sb.updateFavourites(func(m map[string]Favourite) error {
+		f, ok := m[addr]
+		if !ok {
+			return fmt.Errorf("SetFavouriteNote(%s): %w", addr, ErrNotFavourite)
+		}
+		f.Note = strings.TrimSpace(note)
+		m[addr] = f
+		return nil
+	})
+}
+
+// History returns the servers the user joined, most recent first
+func (sb *ServerBook) History() ([]HistoryEntry, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	return sb.history()
+}
+
+// Join records that the user joined the server at addr at time ts
+func (sb *ServerBook) Join(addr, name string, gameID ID, ts time.Time) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	ts = ts.UTC()
+	l, err := sb.history()
+	if err != nil {
+		return err
+	}
+	ent := HistoryEntry{Addr: addr, Name: name, GameID: gameID}
+	for i, e := range l {
+		if e.Addr == addr {
+			ent = e
+			if name != "" {
+				ent.Name = name
+			}
+			if gameID != 0 {
+				ent.GameID = gameID
+			}
+			l = append(l[:i], l[i+1:]...)
+			break
+		}
+	}
+	ent.LastPlayed = ts
+	ent.Joins++
+	l = append([]HistoryEntry{ent}, l...)
+	if len(l) > maxHistory {
+		l = l[:maxHistory]
+	}
+	if err := store.Put(sb.db, historyKey, l); err != nil {
+		return fmt.Errorf("ServerBook.Join: %w", err)
+	}
+
+	m, err := sb.favourites()
+	if err != nil {
+		return err
+	}
+	if f, ok := m[addr]; ok {
+		f.LastPlayed = ts
+		m[addr] = f
+		if err := store.Put(sb.db, favouritesKey, m); err != nil {
+			return fmt.Errorf("ServerBook.Join: %w", err)
+		}
+	}
+	return nil
+}
+
+// serverBrowserHist is the content of Steam's serverbrowser_hist.vdf
+type serverBrowserHist struct {
+	Filters struct {
+		Favorites map[string]serverBrowserEntry
+		History   map[string]serverBrowserEntry
+	}
+}
+
+type serverBrowserEntry struct {
+	Name       string
+	Address    string
+	LastPlayed string
+	AppID      string
+}
+
+func (e serverBrowserEntry) lastPlayed() time.Time {
+	n, _ := strconv.ParseInt(e.LastPlayed, 10, 64)
+	if n <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+func (e serverBrowserEntry) gameID() ID {
+	n, _ := strconv.ParseUint(e.AppID, 10, 32)
+	return ID(n)
+}
+
+// ImportServerBrowserHist imports the favourites and history from Steam's serverbrowser_hist.vdf file fn
+//
+// Servers that are already known keep their tags and notes.
+// It returns the number of favourites and history entries that were added.
+func (sb *ServerBook) ImportServerBrowserHist(fn string) (favs int, hist int, err error) {
+	data, err := ReadVDF[serverBrowserHist](fn)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ImportServerBrowserHist: %w", err)
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	m, err := sb.favourites()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, e := range data.Filters.Favorites {
+		addr := strings.TrimSpace(e.Address)
+		if addr == "" {
+			continue
+		}
+		if _, ok := m[addr]; ok {
+			continue
+		}
+		m[addr] = Favourite{
+			Addr:       addr,
+			Name:       e.Name,
+			GameID:     e.gameID(),
+			Added:      time.Now().UTC(),
+			LastPlayed: e.lastPlayed(),
+		}
+		favs++
+	}
+
+	l, err := sb.history()
+	if err != nil {
+		return 0, 0, err
+	}
+	known := map[string]bool{}
+	for _, e := range l {
+		known[e.Addr] = true
+	}
+	for _, e := range data.Filters.History {
+		addr := strings.TrimSpace(e.Address)
+		if addr == "" || known[addr] {
+			continue
+		}
+		known[addr] = true
+		l = append(l, HistoryEntry{
+			Addr:       addr,
+			Name:       e.Name,
+			GameID:     e.gameID(),
+			LastPlayed: e.lastPlayed(),
+			Joins:      1,
+		})
+		hist++
+	}
+	sort.SliceStable(l, func(i, j int) bool { return l[i].LastPlayed.After(l[j].LastPlayed) })
+	if len(l) > maxHistory {
+		l = l[:maxHistory]
+	}
+
+	if err := store.Put(sb.db, favouritesKey, m); err != nil {
+		return 0, 0, fmt.Errorf("ImportServerBrowserHist: %w", err)
+	}
+	if err := store.Put(sb.db, historyKey, l); err != nil {
+		return 0, 0, fmt.Errorf("ImportServerBrowserHist: %w", err)
+	}
+	return favs, hist, nil
+}
+
+// FindServerBrowserHist returns the paths of Steam's serverbrowser_hist.vdf files
+func FindServerBrowserHist() []string {
+	return FindSteamPaths("config", "serverbrowser_hist.vdf")
+}
@@ -0,0 +1,129 @@
+package steam
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+func TestServerBook(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sb := NewServerBook(db)
+
+	if l, err := sb.Favourites(); err != nil || len(l) != 0 {
+		t.Fatalf("Expected no favourites; Got %v, %v", l, err)
+	}
+
+	if err := sb.AddFavourite("1.2.3.4:27015", "Uncletopia", 440); err != nil {
+		t.Fatal(err)
+	}
+	if err := sb.SetFavouriteTags("1.2.3.4:27015", []string{"payload", " ", "payload", "nocrits"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sb.SetFavouriteNote("1.2.3.4:27015", " friends play here "); err != nil {
+		t.Fatal(err)
+	}
+	if err := sb.SetFavouriteNote("5.6.7.8:27015", "nope"); !errors.Is(err, ErrNotFavourite) {
+		t.Fatalf("Expected ErrNotFavourite; Got %v", err)
+	}
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := sb.Join("1.2.3.4:27015", "", 440, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sb.Join("5.6.7.8:27015", "DoD", 300, ts.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	favs, err := sb.Favourites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favs) != 1 {
+		t.Fatalf("Expected 1 favourite; Got %+v", favs)
+	}
+	f := favs[0]
+	if !f.LastPlayed.Equal(ts) {
+		t.Fatalf("Expected LastPlayed %s; Got %s", ts, f.LastPlayed)
+	}
+	f.Added, f.LastPlayed = time.Time{}, time.Time{}
+	expFav := Favourite{
+		Addr: "1.2.3.4:27015", Name: "Uncletopia", GameID: 440,
+		Tags: []string{"payload", "nocrits"}, Note: "friends play here",
+	}
+	if !reflect.DeepEqual(f, expFav) {
+		t.Fatalf("Expected %+v; Got %+v", expFav, f)
+	}
+
+	hist, err := sb.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expHist := []HistoryEntry{
+		{Addr: "5.6.7.8:27015", Name: "DoD", GameID: 300, LastPlayed: ts.Add(time.Hour), Joins: 1},
+		{Addr: "1.2.3.4:27015", GameID: 440, LastPlayed: ts, Joins: 2},
+	}
+	for i := range hist {
+		if i < len(expHist) && hist[i].LastPlayed.Equal(expHist[i].LastPlayed) {
+			hist[i].LastPlayed = expHist[i].LastPlayed
+		}
+	}
+	if !reflect.DeepEqual(hist, expHist) {
+		t.Fatalf("Expected %+v; Got %+v", expHist, hist)
+	}
+
+	if err := sb.RemoveFavourite("1.2.3.4:27015"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sb.RemoveFavourite("1.2.3.4:27015"); !errors.Is(err, ErrNotFavourite) {
+		t.Fatalf("Expected ErrNotFavourite; Got %v", err)
+	}
+}
+
+func TestImportServerBrowserHist(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sb := NewServerBook(db)
+
+	// known servers keep their details
+	sb.AddFavourite("1.2.3.4:27015", "Mine", 440)
+	sb.SetFavouriteNote("1.2.3.4:27015", "keep me")
+	sb.Join("5.6.7.8:27015", "Mine too", 300, time.Unix(1600000000, 0))
+
+	fn := filepath.Join("testdata", "serverbrowser_hist.vdf")
+	favs, hist, err := sb.ImportServerBrowserHist(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if favs != 1 || hist != 1 {
+		t.Fatalf("Expected 1 favourite and 1 history entry to be imported; Got %d and %d", favs, hist)
+	}
+
+	l, _ := sb.Favourites()
+	if len(l) != 2 || l[0].Name != "DoD:S Classic" || l[0].GameID != 300 || !l[0].LastPlayed.IsZero() || l[1].Note != "keep me" {
+		t.Fatalf("Expected imported and existing favourites; Got %+v", l)
+	}
+
+	h, _ := sb.History()
+	if len(h) != 2 || h[0].Addr != "9.9.9.9:27016" || !h[0].LastPlayed.Equal(time.Unix(1710000000, 0)) || h[1].Name != "Mine too" {
+		t.Fatalf("Expected imported history, most recent first; Got %+v", h)
+	}
+
+	// importing again adds nothing
+	if favs, hist, _ := sb.ImportServerBrowserHist(fn); favs != 0 || hist != 0 {
+		t.Fatalf("Expected nothing to be imported again; Got %d and %d", favs, hist)
+	}
+}
@@ -0,0 +1,183 @@
+package steam
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Filter is a master server query filter
+//
+// The zero value matches every server.
+// See https://developer.valvesoftware.com/wiki/Master_Server_Query_Protocol#Filter
+type Filter struct {
+	// Dedicated matches dedicated servers
+	Dedicated bool `json:"dedicated"`
+	// Secure matches servers using anti-cheat technology (VAC)
+	Secure bool `json:"secure"`
+	// GameDir matches servers running the mod e.g. tf
+	GameDir string `json:"gameDir"`
+	// Map matches servers running the map
+	Map string `json:"map"`
+	// NotEmpty matches servers that aren't empty
+	NotEmpty bool `json:"notEmpty"`
+	// NotFull matches servers that aren't full
+	NotFull bool `json:"notFull"`
+	// NoPassword matches servers that aren't password protected
+	NoPassword bool `json:"noPassword"`
+	// NameMatch matches servers whose hostname matches the pattern, which can use * as a wildcard
+	NameMatch string `json:"nameMatch"`
+	// GameType matches servers with all of the tags in sv_tags
+	GameType []string `json:"gameType"`
+
+	// Nor matches servers that don't match any of its conditions
+	Nor *Filter `json:"nor"`
+	// Nand matches servers that don't match all of its conditions
+	Nand *Filter `json:"nand"`
+}
+
+// filterValue removes characters that can't appear in a filter value
+func filterValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\\' || r == 0 {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// conditions returns the filter's conditions as pairs of key and value
+func (f *Filter) conditions() [][2]string {
+	var l [][2]string
+	add := func(k, v string) {
+		if v = filterValue(v); v != "" {
+			l = append(l, [2]string{k, v})
+		}
+	}
+	flag := func(k string, v bool, on string) {
+		if v {
+			add(k, on)
+		}
+	}
+	flag("dedicated", f.Dedicated, "1")
+	flag("secure", f.Secure, "1")
+	add("gamedir", f.GameDir)
+	add("map", f.Map)
+	flag("empty", f.NotEmpty, "1")
+	flag("full", f.NotFull, "1")
+	flag("password", f.NoPassword, "0")
+	add("name_match", f.NameMatch)
+	var tags []string
+	for _, s := range f.GameType {
+		if s = strings.TrimSpace(filterValue(s)); s != "" {
+			tags = append(tags, strings.ReplaceAll(s, ",", ""))
+		}
+	}
+	add("gametype", strings.Join(tags, ","))
+	for _, g := range []struct {
+		k string
+		f *Filter
+	}{{"nor", f.Nor}, {"nand", f.Nand}} {
+		if g.f == nil {
+			continue
+		}
+		// the group's size is the number of conditions that follow it, including those of nested groups
+		sub := g.f.conditions()
+		if len(sub) == 0 {
+			continue
+		}
+		l = append(l, [2]string{g.k, strconv.Itoa(len(sub))})
+		l = append(l, sub...)
+	}
+	return l
+}
+
+// String returns the filter in the master server filter language e.g. \dedicated\1\map\de_dust2
+func (f Filter) String() string {
+	s := strings.Builder{}
+	for _, c := range f.conditions() {
+		s.WriteString(`\`)
+		s.WriteString(c[0])
+		s.WriteString(`\`)
+		s.WriteString(c[1])
+	}
+	return s.String()
+}
+
+// Query returns the filter string sent to the master server for the game
+func (f Filter) Query(gameID ID) string {
+	return `\appid\` + strconv.FormatUint(uint64(gameID.To32()), 10) + f.String()
+}
+
+// wildcardMatch reports whether s matches the pattern pat, where * matches any run of characters
+func wildcardMatch(pat, s string) bool {
+	pat, s = strings.ToLower(pat), strings.ToLower(s)
+	parts := strings.Split(pat, "*")
+	if len(parts) == 1 {
+		return pat == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
+// results returns whether the server matches each of the filter's conditions, in the order of conditions
+func (f *Filter) results(sr *ServerReply) []bool {
+	if f == nil {
+		return nil
+	}
+	var l []bool
+	test := func(v string, ok func() bool) {
+		if filterValue(v) != "" {
+			l = append(l, ok())
+		}
+	}
+	flag := func(v bool, ok func() bool) {
+		if v {
+			l = append(l, ok())
+		}
+	}
+	flag(f.Dedicated, func() bool { return sr.ServerType == 'd' })
+	flag(f.Secure, func() bool { return sr.VAC == 1 })
+	test(f.GameDir, func() bool { return strings.EqualFold(sr.Folder, filterValue(f.GameDir)) })
+	test(f.Map, func() bool { return strings.EqualFold(sr.Map, filterValue(f.Map)) })
+	flag(f.NotEmpty, func() bool { return sr.Players > 0 })
+	flag(f.NotFull, func() bool { return sr.Players < sr.MaxPlayers })
+	flag(f.NoPassword, func() bool { return sr.Visibility == 0 })
+	test(f.NameMatch, func() bool { return wildcardMatch(filterValue(f.NameMatch), sr.Name) })
+	tags := map[string]bool{}
+	for _, s := range sr.Tags() {
+		tags[strings.ToLower(s)] = true
+	}
+	for _, s := range f.GameType {
+		if s = strings.ReplaceAll(strings.TrimSpace(filterValue(s)), ",", ""); s != "" {
+			l = append(l, tags[strings.ToLower(s)])
+		}
+	}
+	if r := f.Nor.results(sr); len(r) != 0 {
+		// none of the conditions match
+		l = append(l, !slices.Contains(r, true))
+	}
+	if r := f.Nand.results(sr); len(r) != 0 {
+		// not all of the conditions match
+		l = append(l, slices.Contains(r, false))
+	}
+	return l
+}
+
+// Match reports whether the server that sent the reply sr matches the filter
+//
+// It's used to filter servers that weren't listed by the master server e.g. those on the LAN.
+func (f Filter) Match(sr *ServerReply) bool {
+	return !slices.Contains(f.results(sr), false)
+}
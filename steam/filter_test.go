@@ -0,0 +1,128 @@
+package steam
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestFilterString(t *testing.T) {
+	cases := []struct {
+		Filter Filter
+		Exp    string
+	}{
+		{Filter{}, ``},
+		{Filter{Dedicated: true, Secure: true, NotEmpty: true, NotFull: true, NoPassword: true},
+			`\dedicated\1\secure\1\empty\1\full\1\password\0`},
+		{Filter{GameDir: "dod", Map: "dod_anzio", NameMatch: "*Uncletopia*"},
+			`\gamedir\dod\map\dod_anzio\name_match\*Uncletopia*`},
+		{Filter{GameType: []string{"payload", " ", "no,crits"}}, `\gametype\payload,nocrits`},
+		{Filter{Map: `a\b`}, `\map\ab`},
+		{Filter{Nor: &Filter{Map: "cp_orange", NameMatch: "*trade*"}, Nand: &Filter{}},
+			`\nor\2\map\cp_orange\name_match\*trade*`},
+		{Filter{Dedicated: true, Nand: &Filter{Secure: true, Nor: &Filter{Map: "x"}}},
+			`\dedicated\1\nand\3\secure\1\nor\1\map\x`},
+	}
+	for _, c := range cases {
+		if s := c.Filter.String(); s != c.Exp {
+			t.Fatalf("Expected `%s`; Got `%s`", c.Exp, s)
+		}
+	}
+
+	if s := (Filter{Map: "x"}).Query(440); s != `\appid\440\map\x` {
+		t.Fatalf("Expected appid to be prepended; Got `%s`", s)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	sr := &ServerReply{
+		Name:       "Uncletopia | Seattle | 1",
+		Map:        "pl_upward",
+		Folder:     "tf",
+		Players:    23,
+		MaxPlayers: 24,
+		ServerType: 'd',
+		VAC:        1,
+		Keywords:   "alltalk,nocrits,payload",
+	}
+	cases := []struct {
+		Filter Filter
+		Exp    bool
+	}{
+		{Filter{}, true},
+		{Filter{Dedicated: true, Secure: true, NotEmpty: true, NotFull: true, NoPassword: true}, true},
+		{Filter{GameDir: "TF", Map: "PL_Upward"}, true},
+		{Filter{Map: "cp_orange"}, false},
+		{Filter{NameMatch: "*uncletopia*seattle*"}, true},
+		{Filter{NameMatch: "Uncletopia"}, false},
+		{Filter{NameMatch: "*Chicago*"}, false},
+		{Filter{GameType: []string{"payload", "nocrits"}}, true},
+		{Filter{GameType: []string{"payload", "arena"}}, false},
+		{Filter{Nor: &Filter{Map: "cp_orange", NameMatch: "*trade*"}}, true},
+		{Filter{Nor: &Filter{Map: "cp_orange", GameDir: "tf"}}, false},
+		{Filter{Nand: &Filter{Map: "pl_upward", GameDir: "tf"}}, false},
+		{Filter{Nand: &Filter{Map: "pl_upward", GameDir: "dod"}}, true},
+	}
+	for _, c := range cases {
+		if ok := c.Filter.Match(sr); ok != c.Exp {
+			t.Fatalf("Expected `%s` to match=%v; Got %v", c.Filter, c.Exp, ok)
+		}
+	}
+
+	sr.Players, sr.Visibility = 0, 1
+	if (Filter{NotEmpty: true}).Match(sr) || (Filter{NoPassword: true}).Match(sr) {
+		t.Fatal("Expected empty and password protected servers not to match")
+	}
+}
+
+func TestQueryServerList(t *testing.T) {
+	filter := Filter{Dedicated: true, Map: "dod_anzio"}
+	mu := sync.Mutex{}
+	var filters []string
+	page := func(ips ...[6]byte) []byte {
+		p := []byte{0xff, 0xff, 0xff, 0xff, 0x66, 0x0a}
+		for _, ip := range ips {
+			p = append(p, ip[:]...)
+		}
+		return p
+	}
+	addr := serveUDP(t, func(req []byte) [][]byte {
+		// '1', region, seed address, filter
+		l := bytes.Split(req[2:], []byte{0})
+		mu.Lock()
+		filters = append(filters, string(l[1]))
+		mu.Unlock()
+		switch string(l[0]) {
+		case "0.0.0.0:0":
+			return [][]byte{page([6]byte{1, 2, 3, 4, 0x69, 0x87}, [6]byte{5, 6, 7, 8, 0x69, 0x88})}
+		case "5.6.7.8:27016":
+			return [][]byte{page([6]byte{9, 9, 9, 9, 0x69, 0x87}, [6]byte{})}
+		default:
+			return [][]byte{page([6]byte{})}
+		}
+	})
+
+	replies, err := queryServerList(addr, 300, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range replies {
+		var addrs []string
+		for _, r := range l {
+			if r.Region != regions[i] {
+				t.Fatalf("Expected region %s; Got %s", regions[i], r.Region)
+			}
+			addrs = append(addrs, r.String())
+		}
+		if len(addrs) != 3 || addrs[0] != "1.2.3.4:27015" || addrs[2] != "9.9.9.9:27015" {
+			t.Fatalf("Region %s: Expected 3 servers from 2 pages; Got %v", regions[i], addrs)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range filters {
+		if s != `\appid\300\dedicated\1\map\dod_anzio` {
+			t.Fatalf("Expected the filter to be sent to the master server; Got `%s`", s)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package steam
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/amitybell/memio"
+	"github.com/amitybell/srcvox/store"
+)
+
+var (
+	// DefaultLANOptions are the options used for zero-valued LANOptions fields
+	DefaultLANOptions = LANOptions{
+		Ports:   []uint16{27015, 27016, 27017, 27018, 27019, 27020},
+		Timeout: time.Second,
+	}
+)
+
+// LANOptions control the broadcast made by DiscoverLAN
+type LANOptions struct {
+	// Targets are the addresses the query is sent to, the broadcast address of each local IPv4 subnet if empty
+	Targets []netip.Addr
+	// Ports are the ports the query is sent to on each target
+	Ports []uint16
+	// Timeout is how long to wait for replies
+	Timeout time.Duration
+}
+
+func (o LANOptions) withDefaults() LANOptions {
+	d := DefaultLANOptions
+	if len(o.Targets) == 0 {
+		o.Targets = lanBroadcastAddrs()
+	}
+	if len(o.Ports) == 0 {
+		o.Ports = d.Ports
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = d.Timeout
+	}
+	return o
+}
+
+// lanBroadcastAddrs returns the broadcast address of each IPv4 subnet of the up, non-loopback interfaces
+func lanBroadcastAddrs() []netip.Addr {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		Logs.Println("lanBroadcastAddrs:", err)
+		return nil
+	}
+	seen := map[netip.Addr]bool{}
+	var l []netip.Addr
+	for _, ifc := range ifaces {
+		if ifc.Flags&net.FlagUp == 0 || ifc.Flags&net.FlagBroadcast == 0 || ifc.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := ifc.Addrs()
+		for _, a := range addrs {
+			ipn, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip4 := ipn.IP.To4()
+			if ip4 == nil || len(ipn.Mask) != net.IPv4len {
+				continue
+			}
+			var b [4]byte
+			for i := range b {
+				b[i] = ip4[i] | ^ipn.Mask[i]
+			}
+			if ba := netip.AddrFrom4(b); !seen[ba] {
+				seen[ba] = true
+				l = append(l, ba)
+			}
+		}
+	}
+	return l
+}
+
+// lanServer is the state of a server that replied to the broadcast
+type lanServer struct {
+	sent       time.Time
+	challenges int
+	split      splitReassembler
+}
+
+// discoverLAN broadcasts A2S_INFO, and returns the replies by address
+func discoverLAN(ctx context.Context, opts LANOptions) (map[string]*ServerReply, error) {
+	opts = opts.withDefaults()
+	replies := map[string]*ServerReply{}
+	if len(opts.Targets) == 0 {
+		return replies, nil
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("discoverLAN: %w", err)
+	}
+	defer conn.Close()
+
+	send := func(ap netip.AddrPort, challenge int32) {
+		buf := &bytes.Buffer{}
+		query := &ServerQuery{Header: 'T', Payload: "Source Engine Query", Challenge: challenge}
+		query.Encode(buf)
+		// servers that don't exist just don't reply
+		conn.WriteToUDPAddrPort(buf.Bytes(), ap)
+	}
+
+	sent := time.Now()
+	for _, ip := range opts.Targets {
+		for _, port := range opts.Ports {
+			send(netip.AddrPortFrom(ip.Unmap(), port), 0)
+		}
+	}
+
+	servers := map[netip.AddrPort]*lanServer{}
+	conn.SetReadDeadline(sent.Add(opts.Timeout))
+	// set after the timeout, so cancellation isn't overridden
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	p := make([]byte, 2<<10)
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(p)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				break
+			}
+			// e.g. ICMP port unreachable on Windows
+			continue
+		}
+		from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+		addr := from.String()
+		if replies[addr] != nil {
+			continue
+		}
+		srv := servers[from]
+		if srv == nil {
+			srv = &lanServer{sent: sent}
+			servers[from] = srv
+		}
+
+		pkt := p[:n]
+		if isSplitPacket(pkt) {
+			reply, err := srv.split.Add(pkt)
+			if err != nil || reply == nil {
+				continue
+			}
+			pkt = reply
+		}
+
+		rep := &ServerReply{Ts: time.Now()}
+		err = rep.Decode(memio.NewFile(pkt))
+		switch {
+		case err == nil:
+			rep.Ping = rep.Ts.Sub(srv.sent)
+			replies[addr] = rep
+		case errors.Is(err, ErrChallenge) && srv.challenges < 3:
+			srv.challenges++
+			srv.sent = time.Now()
+			srv.split = splitReassembler{}
+			send(from, rep.Challenge)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("discoverLAN: %w", err)
+	}
+	return replies, nil
+}
+
+// DiscoverLAN broadcasts A2S_INFO on the local subnets, and returns the info of the servers that reply
+func DiscoverLAN(ctx context.Context, opts LANOptions) (map[string]ServerInfo, error) {
+	replies, err := discoverLAN(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]ServerInfo, len(replies))
+	for addr, rep := range replies {
+		m[addr] = newServerInfo(LAN, addr, rep)
+	}
+	return m, nil
+}
+
+// QueryLANServerList returns the address of the LAN servers for the game that match filter, with the LAN region
+//
+// The filter is applied to the replies, as there's no master server to apply it.
+// The info of each server is cached, so it doesn't need to be queried again.
+// Discovery is cancelled if ctx is cancelled, and the list isn't cached.
+func QueryLANServerList(ctx context.Context, db *store.DB, maxAge time.Duration, gameID ID, filter Filter, opts LANOptions) (map[string]Region, error) {
+	key := fmt.Sprintf("/serverList/lan/%s", filter.Query(gameID))
+	m, err := store.CacheTTL(db, maxAge, key, 1, func() (map[string]Region, error) {
+		replies, err := discoverLAN(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		addrs := map[string]Region{}
+		for addr, rep := range replies {
+			if gameID != 0 && ID(rep.AppID()) != gameID {
+				continue
+			}
+			rep.Region = LAN
+			_ = store.Put(db, serverInfoKey(addr), store.CacheEntry[*ServerReply]{Ts: time.Now().UTC(), V: rep, Ver: serverInfoVer})
+			if filter.Match(rep) {
+				addrs[addr] = LAN
+			}
+		}
+		return addrs, nil
+	})
+	if err != nil && !errors.Is(err, store.ErrStale) {
+		return m, fmt.Errorf("QueryLANServerList: %w", err)
+	}
+	return m, nil
+}
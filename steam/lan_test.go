@@ -0,0 +1,85 @@
+package steam
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+func TestDiscoverLAN(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tf2, err := os.ReadFile(filepath.Join("testdata", "info-tf2.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	csgo, err := os.ReadFile(filepath.Join("testdata", "info-csgo.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	direct := serveUDP(t, func(req []byte) [][]byte { return [][]byte{tf2} })
+	challenged := serveUDP(t, func(req []byte) [][]byte {
+		if bytes.HasSuffix(req, playersChallengePacket[5:]) {
+			return [][]byte{csgo}
+		}
+		return [][]byte{playersChallengePacket}
+	})
+	silent := serveUDP(t, func(req []byte) [][]byte { return nil })
+
+	var ports []uint16
+	for _, s := range []string{direct, challenged, silent} {
+		ports = append(ports, netip.MustParseAddrPort(s).Port())
+	}
+	opts := LANOptions{
+		Targets: []netip.Addr{netip.MustParseAddr("127.0.0.1")},
+		Ports:   ports,
+		Timeout: 200 * time.Millisecond,
+	}
+
+	m, err := DiscoverLAN(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m[direct].AppID != 440 || m[challenged].AppID != 730 || m[direct].Region != LAN {
+		t.Fatalf("Expected 2 LAN servers; Got %+v", m)
+	}
+
+	l, err := QueryLANServerList(context.Background(), db, time.Minute, 440, Filter{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[direct] != LAN {
+		t.Fatalf("Expected the TF2 server in the LAN region; Got %v", l)
+	}
+	if inf, ok := cachedServerInfo(db, time.Minute, LAN, direct); !ok || inf.Name != "Uncletopia | Seattle | 1" {
+		t.Fatalf("Expected the LAN server info to be cached; Got %+v", inf)
+	}
+
+	// the filter is applied to the replies
+	if l, err := QueryLANServerList(context.Background(), db, time.Minute, 440, Filter{Map: "pl_upward", NotEmpty: true}, opts); err != nil || len(l) != 1 {
+		t.Fatalf("Expected the TF2 server to match the filter; Got %v, %v", l, err)
+	}
+	if l, err := QueryLANServerList(context.Background(), db, time.Minute, 440, Filter{Map: "cp_orange"}, opts); err != nil || len(l) != 0 {
+		t.Fatalf("Expected no servers to match the filter; Got %v, %v", l, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DiscoverLAN(ctx, LANOptions{Targets: opts.Targets, Ports: ports, Timeout: time.Minute}); err == nil {
+		t.Fatal("Expected a cancelled discovery to fail")
+	}
+	if _, err := QueryLANServerList(ctx, db, time.Minute, 440, Filter{Map: "ctf_2fort"}, LANOptions{Targets: opts.Targets, Ports: ports, Timeout: time.Minute}); err == nil {
+		t.Fatal("Expected a cancelled server list query to fail")
+	}
+}
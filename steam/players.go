@@ -0,0 +1,172 @@
+package steam
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/amitybell/memio"
+	"github.com/amitybell/srcvox/demo"
+	"github.com/amitybell/srcvox/store"
+)
+
+// PlayerQuery is an A2S_PLAYER query
+type PlayerQuery struct {
+	// Challenge is -1 to request a challenge
+	Challenge int32
+
+	buf memio.File
+}
+
+func (pq *PlayerQuery) Encode(w io.Writer) error {
+	r := &pq.buf
+	r.Seek(0, 0)
+	r.WriteInt32(binary.LittleEndian, -1)
+	r.WriteByte('U')
+	r.WriteInt32(binary.LittleEndian, pq.Challenge)
+	r.Seek(0, 0)
+	_, err := io.Copy(w, r)
+	if err != nil {
+		return fmt.Errorf("PlayerQuery.Encode: %w", err)
+	}
+	return nil
+}
+
+// ServerPlayer is a player on a server, as reported by A2S_PLAYER
+type ServerPlayer struct {
+	// Name is empty for players that are still connecting
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	// Duration is how long the player has been connected
+	Duration time.Duration `json:"duration"`
+}
+
+type PlayersReply struct {
+	// 'D' (0x44) for players, or 'A' (0x41) for a challenge
+	Header byte
+
+	Players []ServerPlayer
+
+	Challenge int32
+
+	Ts time.Time
+}
+
+func (pr *PlayersReply) decodeChallenge(r *memio.File) error {
+	var err error
+	pr.Challenge, err = r.ReadInt32(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("PlayersReply.Decode: Challenge: %w", err)
+	}
+	return ErrChallenge
+}
+
+func (pr *PlayersReply) decodePlayers(r *memio.File) error {
+	n, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("PlayersReply.Decode: Players: %w", err)
+	}
+
+	pr.Players = make([]ServerPlayer, 0, n)
+	for i := 0; i < int(n); i++ {
+		// the index is always 0 on most servers
+		if _, err := r.ReadByte(); err != nil {
+			return fmt.Errorf("PlayersReply.Decode: Player %d: Index: %w", i, err)
+		}
+
+		name, err := r.ReadString(0)
+		if err != nil {
+			return fmt.Errorf("PlayersReply.Decode: Player %d: Name: %w", i, err)
+		}
+
+		score, err := r.ReadInt32(binary.LittleEndian)
+		if err != nil {
+			return fmt.Errorf("PlayersReply.Decode: Player %d: Score: %w", i, err)
+		}
+
+		secs, err := r.ReadFloat32(binary.LittleEndian)
+		if err != nil {
+			return fmt.Errorf("PlayersReply.Decode: Player %d: Duration: %w", i, err)
+		}
+		if math.IsNaN(float64(secs)) || secs < 0 {
+			secs = 0
+		}
+
+		pr.Players = append(pr.Players, ServerPlayer{
+			Name:     name,
+			Score:    int(score),
+			Duration: time.Duration(float64(secs) * float64(time.Second)),
+		})
+	}
+	return nil
+}
+
+func (pr *PlayersReply) Decode(r *memio.File) error {
+	pfx, err := r.ReadInt32(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("PlayersReply.Decode: Pfx: %w", err)
+	}
+	if pfx != -1 {
+		return fmt.Errorf("PlayersReply.Decode: %w: %v", ErrUnknownPrefix, pfx)
+	}
+
+	pr.Header, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("PlayersReply.Decode: Header: %w", err)
+	}
+	switch pr.Header {
+	case 'D':
+		return pr.decodePlayers(r)
+	case 'A':
+		return pr.decodeChallenge(r)
+	default:
+		return fmt.Errorf("PlayersReply.Decode: %w: %c", ErrUnknownHeader, pr.Header)
+	}
+}
+
+func queryPlayers(addr string) (*PlayersReply, error) {
+	conn, err := dialUDP(addr)
+	if err != nil {
+		return nil, fmt.Errorf("queryPlayers(%s): %w", addr, err)
+	}
+	defer conn.Close()
+
+	buf := memio.NewFile(nil)
+
+	challenge := int32(-1)
+	for i := 0; ; i++ {
+		reply := &PlayersReply{Ts: time.Now()}
+		query := &PlayerQuery{Challenge: challenge}
+		_, err := queryConn(conn, query, buf, reply)
+		switch {
+		case err == nil:
+			return reply, nil
+		case errors.Is(err, ErrChallenge) && i < 3:
+			challenge = reply.Challenge
+		default:
+			return nil, fmt.Errorf("queryPlayers(%s): %w", addr, err)
+		}
+	}
+}
+
+func serverPlayersKey(addr string) string {
+	return fmt.Sprintf("/serverPlayers/%s", addr)
+}
+
+// QueryPlayers returns the players on the server at addr
+func QueryPlayers(db *store.DB, maxAge time.Duration, addr string) ([]ServerPlayer, error) {
+	if demo.Enabled {
+		maxAge = -1
+	}
+
+	rep, err := store.CacheTTL(db, maxAge, serverPlayersKey(addr), 1, func() (*PlayersReply, error) {
+		return queryPlayers(addr)
+	})
+	if err != nil && (!errors.Is(err, store.ErrStale) || rep == nil) {
+		return nil, err
+	}
+	return rep.Players, nil
+}
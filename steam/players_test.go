@@ -0,0 +1,105 @@
+package steam
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amitybell/memio"
+)
+
+var (
+	// recorded A2S_PLAYER replies
+	playersChallengePacket = []byte{
+		0xff, 0xff, 0xff, 0xff, 0x41, 0x4b, 0xa1, 0xd5, 0x22,
+	}
+	playersPacket = []byte{
+		0xff, 0xff, 0xff, 0xff, 0x44, 0x02,
+		// 0, "Kyle", 12, 3600.5
+		0x00, 0x4b, 0x79, 0x6c, 0x65, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x08, 0x61, 0x45,
+		// 0, "", -1, 1.5
+		0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0xc0, 0x3f,
+	}
+	expPlayers = []ServerPlayer{
+		{Name: "Kyle", Score: 12, Duration: 3600*time.Second + 500*time.Millisecond},
+		{Name: "", Score: -1, Duration: 1500 * time.Millisecond},
+	}
+)
+
+func TestPlayersReplyDecode(t *testing.T) {
+	rep := &PlayersReply{}
+	err := rep.Decode(memio.NewFile(playersChallengePacket))
+	if !errors.Is(err, ErrChallenge) {
+		t.Fatalf("Expected ErrChallenge; Got %v", err)
+	}
+	if rep.Challenge != 0x22d5a14b {
+		t.Fatalf("Expected challenge %x; Got %x", 0x22d5a14b, rep.Challenge)
+	}
+
+	rep = &PlayersReply{}
+	if err := rep.Decode(memio.NewFile(playersPacket)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rep.Players, expPlayers) {
+		t.Fatalf("Expected %+v; Got %+v", expPlayers, rep.Players)
+	}
+
+	truncated := playersPacket[:len(playersPacket)-3]
+	if err := (&PlayersReply{}).Decode(memio.NewFile(truncated)); err == nil {
+		t.Fatal("Expected truncated reply to fail")
+	}
+}
+
+// serveUDP starts a fake server that replies to each request with the packets returned by reply
+func serveUDP(t *testing.T, reply func(req []byte) [][]byte) string {
+	return serveUDPFrom(t, func(_ *net.UDPAddr, req []byte) [][]byte { return reply(req) })
+}
+
+// serveUDPFrom is like serveUDP, but reply is also passed the address of the client
+func serveUDPFrom(t *testing.T, reply func(from *net.UDPAddr, req []byte) [][]byte) string {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1400)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			for _, p := range reply(addr, buf[:n]) {
+				conn.WriteToUDP(p, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestQueryPlayers(t *testing.T) {
+	challengeReq := []byte{0xff, 0xff, 0xff, 0xff, 'U', 0xff, 0xff, 0xff, 0xff}
+	playersReq := []byte{0xff, 0xff, 0xff, 0xff, 'U', 0x4b, 0xa1, 0xd5, 0x22}
+	addr := serveUDP(t, func(req []byte) [][]byte {
+		switch {
+		case bytes.Equal(req, challengeReq):
+			return [][]byte{playersChallengePacket}
+		case bytes.Equal(req, playersReq):
+			return [][]byte{playersPacket}
+		default:
+			return [][]byte{{0xff, 0xff, 0xff, 0xff, '?'}}
+		}
+	})
+
+	rep, err := queryPlayers(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rep.Players, expPlayers) {
+		t.Fatalf("Expected %+v; Got %+v", expPlayers, rep.Players)
+	}
+}
@@ -0,0 +1,345 @@
+package steam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+const (
+	// popRawRetention is how long samples are kept at full resolution, before they're downsampled to hourly samples
+	popRawRetention = 48 * time.Hour
+
+	// popRetention is how long samples are kept
+	popRetention = 30 * 24 * time.Hour
+
+	// popBucket is the size of downsampled samples
+	popBucket = time.Hour
+
+	// popRecentJoin is how recently a server must have been joined for the sampler to query it
+	popRecentJoin = 14 * 24 * time.Hour
+
+	// maxPopServers is the number of servers queried by the sampler
+	maxPopServers = 100
+)
+
+var (
+	// popMu serialises updates to the population series
+	popMu sync.Mutex
+)
+
+// PopSample is a sample of a server's population
+type PopSample struct {
+	Ts      time.Time `json:"ts"`
+	Players int       `json:"players"`
+	Bots    int       `json:"bots"`
+	PingMs  int       `json:"ping"`
+	Map     string    `json:"map"`
+	// Samples is the number of samples this one is the average of
+	Samples int `json:"samples"`
+}
+
+// MapPlay is a period of time a map was played on a server
+type MapPlay struct {
+	Map   string    `json:"map"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// PopHistory is the population history of a server
+type PopHistory struct {
+	Addr    string      `json:"addr"`
+	Samples []PopSample `json:"samples"`
+	// Rotation is the order maps were played in, derived from map changes
+	Rotation []MapPlay `json:"rotation"`
+	// ByHour is the average number of players at each hour of the day, in local time
+	ByHour []float64 `json:"byHour"`
+}
+
+// popSeries is the stored population series of a server
+//
+// Map names are stored once, and referenced by index in the samples.
+type popSeries struct {
+	Maps    []string    `msgpack:"m"`
+	Samples []popSample `msgpack:"s"`
+}
+
+type popSample struct {
+	Ts      int64  `msgpack:"t"`
+	Players uint16 `msgpack:"p"`
+	Bots    uint16 `msgpack:"b"`
+	Ping    uint16 `msgpack:"g"`
+	Map     uint16 `msgpack:"m"`
+	// N is the number of samples this one was downsampled from
+	N uint16 `msgpack:"n"`
+}
+
+func popKey(addr string) string {
+	return fmt.Sprintf("/serverPop/%s", addr)
+}
+
+func clampUint16(n int) uint16 {
+	return uint16(min(max(n, 0), math.MaxUint16))
+}
+
+func (ps *popSeries) mapIndex(name string) uint16 {
+	for i, s := range ps.Maps {
+		if s == name {
+			return uint16(i)
+		}
+	}
+	ps.Maps = append(ps.Maps, name)
+	return uint16(len(ps.Maps) - 1)
+}
+
+func (ps *popSeries) mapName(i uint16) string {
+	if int(i) < len(ps.Maps) {
+		return ps.Maps[i]
+	}
+	return ""
+}
+
+// compact removes expired samples, and downsamples those older than popRawRetention
+func (ps *popSeries) compact(now time.Time) {
+	cutoff := now.Add(-popRawRetention).Unix()
+	expire := now.Add(-popRetention).Unix()
+	bucketSize := int64(popBucket / time.Second)
+
+	var samples []popSample
+	var acc popSample
+	var sumP, sumB, sumG, sumN int
+	flush := func() {
+		if sumN == 0 {
+			return
+		}
+		acc.Players = clampUint16((sumP + sumN/2) / sumN)
+		acc.Bots = clampUint16((sumB + sumN/2) / sumN)
+		acc.Ping = clampUint16((sumG + sumN/2) / sumN)
+		acc.N = clampUint16(sumN)
+		samples = append(samples, acc)
+		sumP, sumB, sumG, sumN = 0, 0, 0, 0
+	}
+	for _, s := range ps.Samples {
+		if s.Ts < expire {
+			continue
+		}
+		if s.Ts >= cutoff {
+			flush()
+			samples = append(samples, s)
+			continue
+		}
+		bucket := s.Ts - s.Ts%bucketSize
+		if sumN != 0 && acc.Ts != bucket {
+			flush()
+		}
+		n := max(int(s.N), 1)
+		acc.Ts = bucket
+		// the map played most recently in the bucket
+		acc.Map = s.Map
+		sumP += int(s.Players) * n
+		sumB += int(s.Bots) * n
+		sumG += int(s.Ping) * n
+		sumN += n
+	}
+	flush()
+
+	// remove the maps that are no longer referenced
+	used := map[uint16]uint16{}
+	var maps []string
+	for i, s := range samples {
+		j, ok := used[s.Map]
+		if !ok {
+			j = uint16(len(maps))
+			used[s.Map] = j
+			maps = append(maps, ps.mapName(s.Map))
+		}
+		samples[i].Map = j
+	}
+	ps.Maps, ps.Samples = maps, samples
+}
+
+func readPopSeries(db *store.DB, addr string) (popSeries, error) {
+	ps, err := store.Get[popSeries](db, popKey(addr))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return ps, fmt.Errorf("readPopSeries: %w", err)
+	}
+	return ps, nil
+}
+
+// RecordPopulation adds a sample of the server's info to its population history
+func RecordPopulation(db *store.DB, inf ServerInfo) error {
+	popMu.Lock()
+	defer popMu.Unlock()
+
+	ps, err := readPopSeries(db, inf.Addr)
+	if err != nil {
+		return fmt.Errorf("RecordPopulation: %w", err)
+	}
+	ts := inf.Ts.Unix()
+	if n := len(ps.Samples); n != 0 && ps.Samples[n-1].Ts >= ts {
+		// e.g. the same cached reply
+		return nil
+	}
+	ps.Samples = append(ps.Samples, popSample{
+		Ts:      ts,
+		Players: clampUint16(inf.Players),
+		Bots:    clampUint16(inf.Bots),
+		Ping:    clampUint16(inf.PingMs),
+		Map:     ps.mapIndex(inf.Map),
+		N:       1,
+	})
+	ps.compact(time.Now())
+	if err := store.Put(db, popKey(inf.Addr), ps); err != nil {
+		return fmt.Errorf("RecordPopulation: %w", err)
+	}
+	return nil
+}
+
+// PopulationHistory returns the population history of the server at addr since time since
+func PopulationHistory(db *store.DB, addr string, since time.Time) (PopHistory, error) {
+	ps, err := readPopSeries(db, addr)
+	if err != nil {
+		return PopHistory{}, fmt.Errorf("PopulationHistory: %w", err)
+	}
+
+	h := PopHistory{Addr: addr, ByHour: make([]float64, 24)}
+	var hourSum, hourN [24]int
+	for _, s := range ps.Samples {
+		ts := time.Unix(s.Ts, 0)
+		if ts.Before(since) {
+			continue
+		}
+		n := max(int(s.N), 1)
+		p := PopSample{
+			Ts:      ts,
+			Players: int(s.Players),
+			Bots:    int(s.Bots),
+			PingMs:  int(s.Ping),
+			Map:     ps.mapName(s.Map),
+			Samples: n,
+		}
+		h.Samples = append(h.Samples, p)
+
+		hr := ts.Local().Hour()
+		hourSum[hr] += p.Players * n
+		hourN[hr] += n
+
+		switch r := h.Rotation; {
+		case len(r) != 0 && r[len(r)-1].Map == p.Map:
+			r[len(r)-1].End = ts
+		default:
+			h.Rotation = append(h.Rotation, MapPlay{Map: p.Map, Start: ts, End: ts})
+		}
+	}
+	for i := range h.ByHour {
+		if hourN[i] != 0 {
+			h.ByHour[i] = float64(hourSum[i]) / float64(hourN[i])
+		}
+	}
+	return h, nil
+}
+
+// PopulationSampler periodically samples the population of the user's favourite and recently joined servers
+type PopulationSampler struct {
+	DB    *store.DB
+	Book  *ServerBook
+	Every time.Duration
+	// Scan are the options used to query the servers
+	Scan ScanOptions
+	// Watch evaluates its rules against each sample, if it's not nil
+	Watch *Watcher
+}
+
+// region returns the region of the server at addr, from its last cached info
+//
+// Servers that were never scanned get the zero value.
+func (pop *PopulationSampler) region(addr string) Region {
+	inf, _ := CachedServerInfo(pop.DB, addr)
+	return inf.Region
+}
+
+// servers returns the servers to sample
+func (pop *PopulationSampler) servers() map[string]Region {
+	m := map[string]Region{}
+	favs, _ := pop.Book.Favourites()
+	for _, f := range favs {
+		m[f.Addr] = pop.region(f.Addr)
+	}
+	hist, _ := pop.Book.History()
+	for _, e := range hist {
+		if len(m) >= maxPopServers {
+			break
+		}
+		if time.Since(e.LastPlayed) <= popRecentJoin {
+			m[e.Addr] = pop.region(e.Addr)
+		}
+	}
+	if pop.Watch != nil {
+		for _, addr := range pop.Watch.Addrs() {
+			m[addr] = pop.region(addr)
+		}
+	}
+	return m
+}
+
+// Sample queries the servers once, and records their population
+func (pop *PopulationSampler) Sample(ctx context.Context) error {
+	servers := pop.servers()
+	if pop.Watch != nil {
+		pop.Watch.prune(servers)
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+	sc := &Scanner{
+		DB: pop.DB,
+		// reuse replies to other queries made since the last sample
+		MaxAge:  pop.Every / 2,
+		Options: pop.Scan,
+	}
+	var errs []error
+	var infos []ServerInfo
+	err := sc.Scan(ctx, servers, func(inf ServerInfo) {
+		infos = append(infos, inf)
+		if err := RecordPopulation(pop.DB, inf); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if pop.Watch != nil {
+		// the rules are checked after the scan, so player queries don't delay it
+		for _, inf := range infos {
+			if err := pop.Watch.Check(ctx, inf); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("PopulationSampler.Sample: %w", err)
+	}
+	return nil
+}
+
+// Run samples the servers every pop.Every until ctx is cancelled
+func (pop *PopulationSampler) Run(ctx context.Context) {
+	tick := time.NewTicker(pop.Every)
+	defer tick.Stop()
+
+	for {
+		if err := pop.Sample(ctx); err != nil && ctx.Err() == nil {
+			Logs.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
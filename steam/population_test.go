@@ -0,0 +1,120 @@
+package steam
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+func TestPopSeriesCompact(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	hour := now.Add(-72 * time.Hour).Truncate(time.Hour).Unix()
+	ps := popSeries{Maps: []string{"old", "ctf_2fort", "pl_upward", "cp_dustbowl"}}
+	ps.Samples = []popSample{
+		// expired
+		{Ts: now.Add(-31 * 24 * time.Hour).Unix(), Map: 0, N: 1},
+		// downsampled into a single hour
+		{Ts: hour + 60, Players: 10, Bots: 2, Ping: 50, Map: 1, N: 1},
+		{Ts: hour + 1800, Players: 20, Bots: 0, Ping: 60, Map: 2, N: 1},
+		{Ts: hour + 3000, Players: 25, Bots: 1, Ping: 70, Map: 2, N: 2},
+		// kept as is
+		{Ts: now.Add(-time.Hour).Unix(), Players: 5, Ping: 40, Map: 3, N: 1},
+	}
+	ps.compact(now)
+	ps.compact(now)
+
+	exp := popSeries{
+		Maps: []string{"pl_upward", "cp_dustbowl"},
+		Samples: []popSample{
+			{Ts: hour, Players: 20, Bots: 1, Ping: 63, Map: 0, N: 4},
+			{Ts: now.Add(-time.Hour).Unix(), Players: 5, Ping: 40, Map: 1, N: 1},
+		},
+	}
+	if !reflect.DeepEqual(ps, exp) {
+		t.Fatalf("Expected %+v; Got %+v", exp, ps)
+	}
+}
+
+func TestPopulationHistory(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	addr := "1.2.3.4:27015"
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	maps := []string{"ctf_2fort", "ctf_2fort", "pl_upward", "pl_upward", "ctf_2fort"}
+	for i, m := range maps {
+		inf := ServerInfo{Addr: addr, Players: i, Bots: 1, PingMs: 30, Map: m, Ts: start.Add(time.Duration(i) * time.Minute)}
+		if err := RecordPopulation(db, inf); err != nil {
+			t.Fatal(err)
+		}
+		// the same reply is only recorded once
+		if err := RecordPopulation(db, inf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := PopulationHistory(db, addr, start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Samples) != 4 || h.Samples[0].Players != 1 || h.Samples[3].Map != "ctf_2fort" {
+		t.Fatalf("Expected 4 samples since the window start; Got %+v", h.Samples)
+	}
+	rot := []string{}
+	for _, p := range h.Rotation {
+		rot = append(rot, p.Map)
+	}
+	if !reflect.DeepEqual(rot, []string{"ctf_2fort", "pl_upward", "ctf_2fort"}) {
+		t.Fatalf("Expected the map rotation to follow map changes; Got %+v", h.Rotation)
+	}
+	if p := h.Rotation[1]; !p.Start.Equal(start.Add(2*time.Minute)) || !p.End.Equal(start.Add(3*time.Minute)) {
+		t.Fatalf("Expected pl_upward to be played from minute 2 to 3; Got %+v", p)
+	}
+	busy := 0.0
+	for _, n := range h.ByHour {
+		busy += n
+	}
+	if len(h.ByHour) != 24 || busy == 0 {
+		t.Fatalf("Expected players by hour; Got %v", h.ByHour)
+	}
+
+	if h, err := PopulationHistory(db, "5.6.7.8:27015", start); err != nil || len(h.Samples) != 0 {
+		t.Fatalf("Expected no history for an unknown server; Got %+v, %v", h, err)
+	}
+}
+
+func TestPopulationSamplerServers(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	book := NewServerBook(db)
+	scanned, unknown, joined := "1.2.3.4:27015", "1.2.3.5:27015", "1.2.3.6:27015"
+	for _, addr := range []string{scanned, unknown} {
+		if err := book.AddFavourite(addr, addr, 440); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := book.Join(joined, joined, 440, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for addr, region := range map[string]Region{scanned: Europe, joined: Australia} {
+		rep := &ServerReply{Name: addr, Region: region}
+		if err := store.Put(db, serverInfoKey(addr), store.CacheEntry[*ServerReply]{Ts: time.Now().UTC(), V: rep, Ver: serverInfoVer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pop := &PopulationSampler{DB: db, Book: book}
+	exp := map[string]Region{scanned: Europe, unknown: 0, joined: Australia}
+	if m := pop.servers(); !reflect.DeepEqual(m, exp) {
+		t.Fatalf("Expected the regions of the last scans %v; Got %v", exp, m)
+	}
+}
@@ -0,0 +1,152 @@
+package steam
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/amitybell/memio"
+	"github.com/amitybell/srcvox/demo"
+	"github.com/amitybell/srcvox/store"
+)
+
+// ServerRules are the public cvars of a server e.g. mp_friendlyfire, keyed by name
+type ServerRules map[string]string
+
+// RulesQuery is an A2S_RULES query
+type RulesQuery struct {
+	// Challenge is -1 to request a challenge
+	Challenge int32
+
+	buf memio.File
+}
+
+func (rq *RulesQuery) Encode(w io.Writer) error {
+	r := &rq.buf
+	r.Seek(0, 0)
+	r.WriteInt32(binary.LittleEndian, -1)
+	r.WriteByte('V')
+	r.WriteInt32(binary.LittleEndian, rq.Challenge)
+	r.Seek(0, 0)
+	_, err := io.Copy(w, r)
+	if err != nil {
+		return fmt.Errorf("RulesQuery.Encode: %w", err)
+	}
+	return nil
+}
+
+type RulesReply struct {
+	// 'E' (0x45) for rules, or 'A' (0x41) for a challenge
+	Header byte
+
+	Rules ServerRules
+
+	Challenge int32
+
+	Ts time.Time
+}
+
+func (rr *RulesReply) decodeChallenge(r *memio.File) error {
+	var err error
+	rr.Challenge, err = r.ReadInt32(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("RulesReply.Decode: Challenge: %w", err)
+	}
+	return ErrChallenge
+}
+
+func (rr *RulesReply) decodeRules(r *memio.File) error {
+	n, err := r.ReadUint16(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("RulesReply.Decode: Rules: %w", err)
+	}
+
+	rr.Rules = make(ServerRules, n)
+	for i := 0; i < int(n); i++ {
+		// some servers report more rules than they send
+		if int(r.Offset()) >= r.Len() {
+			break
+		}
+
+		name, err := r.ReadString(0)
+		if err != nil {
+			return fmt.Errorf("RulesReply.Decode: Rule %d: Name: %w", i, err)
+		}
+
+		val, err := r.ReadString(0)
+		if err != nil {
+			return fmt.Errorf("RulesReply.Decode: Rule %d: Value: %w", i, err)
+		}
+		rr.Rules[name] = val
+	}
+	return nil
+}
+
+func (rr *RulesReply) Decode(r *memio.File) error {
+	pfx, err := r.ReadInt32(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("RulesReply.Decode: Pfx: %w", err)
+	}
+	if pfx != -1 {
+		return fmt.Errorf("RulesReply.Decode: %w: %v", ErrUnknownPrefix, pfx)
+	}
+
+	rr.Header, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("RulesReply.Decode: Header: %w", err)
+	}
+	switch rr.Header {
+	case 'E':
+		return rr.decodeRules(r)
+	case 'A':
+		return rr.decodeChallenge(r)
+	default:
+		return fmt.Errorf("RulesReply.Decode: %w: %c", ErrUnknownHeader, rr.Header)
+	}
+}
+
+func queryRules(addr string) (ServerRules, error) {
+	conn, err := dialUDP(addr)
+	if err != nil {
+		return nil, fmt.Errorf("queryRules(%s): %w", addr, err)
+	}
+	defer conn.Close()
+
+	buf := memio.NewFile(nil)
+
+	challenge := int32(-1)
+	for i := 0; ; i++ {
+		reply := &RulesReply{Ts: time.Now()}
+		query := &RulesQuery{Challenge: challenge}
+		_, err := queryConn(conn, query, buf, reply)
+		switch {
+		case err == nil:
+			return reply.Rules, nil
+		case errors.Is(err, ErrChallenge) && i < 3:
+			challenge = reply.Challenge
+		default:
+			return nil, fmt.Errorf("queryRules(%s): %w", addr, err)
+		}
+	}
+}
+
+func serverRulesKey(addr string) string {
+	return fmt.Sprintf("/serverRules/%s", addr)
+}
+
+// QueryRules returns the rules of the server at addr
+func QueryRules(db *store.DB, maxAge time.Duration, addr string) (ServerRules, error) {
+	if demo.Enabled {
+		maxAge = -1
+	}
+
+	rules, err := store.CacheTTL(db, maxAge, serverRulesKey(addr), 1, func() (ServerRules, error) {
+		return queryRules(addr)
+	})
+	if err != nil && (!errors.Is(err, store.ErrStale) || rules == nil) {
+		return nil, err
+	}
+	return rules, nil
+}
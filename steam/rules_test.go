@@ -0,0 +1,100 @@
+package steam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/amitybell/memio"
+)
+
+var (
+	// recorded A2S_RULES reply, before it's split
+	rulesPacket = append([]byte{0xff, 0xff, 0xff, 0xff, 0x45, 0x03, 0x00},
+		"mp_friendlyfire\x001\x00mp_roundtime\x002.5\x00sm_nextmap\x00dod_anzio\x00"...)
+	expRules = ServerRules{
+		"mp_friendlyfire": "1",
+		"mp_roundtime":    "2.5",
+		"sm_nextmap":      "dod_anzio",
+	}
+)
+
+// splitPackets splits p into n Source-style split packets
+func splitPackets(id int32, p []byte, n int) [][]byte {
+	var l [][]byte
+	size := (len(p) + n - 1) / n
+	for i := 0; i < n; i++ {
+		part := p[i*size : min(len(p), (i+1)*size)]
+		b := memio.NewFile(nil)
+		b.WriteInt32(binary.LittleEndian, -2)
+		b.WriteInt32(binary.LittleEndian, id)
+		b.WriteByte(byte(n))
+		b.WriteByte(byte(i))
+		b.WriteInt16(binary.LittleEndian, 1248)
+		b.Write(part)
+		l = append(l, b.Bytes())
+	}
+	return l
+}
+
+func TestRulesReplyDecode(t *testing.T) {
+	rep := &RulesReply{}
+	if err := rep.Decode(memio.NewFile(rulesPacket)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rep.Rules, expRules) {
+		t.Fatalf("Expected %+v; Got %+v", expRules, rep.Rules)
+	}
+
+	// the count is sometimes larger than the number of rules sent
+	short := append([]byte{0xff, 0xff, 0xff, 0xff, 0x45, 0x05, 0x00}, "a\x00b\x00"...)
+	rep = &RulesReply{}
+	if err := rep.Decode(memio.NewFile(short)); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (ServerRules{"a": "b"}); !reflect.DeepEqual(rep.Rules, exp) {
+		t.Fatalf("Expected %+v; Got %+v", exp, rep.Rules)
+	}
+
+	// counts above 32767 are unsigned
+	large := append([]byte{0xff, 0xff, 0xff, 0xff, 0x45, 0x00, 0x80}, "a\x00b\x00"...)
+	rep = &RulesReply{}
+	if err := rep.Decode(memio.NewFile(large)); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (ServerRules{"a": "b"}); !reflect.DeepEqual(rep.Rules, exp) {
+		t.Fatalf("Expected %+v; Got %+v", exp, rep.Rules)
+	}
+
+	rep = &RulesReply{}
+	if err := rep.Decode(memio.NewFile(playersChallengePacket)); !errors.Is(err, ErrChallenge) {
+		t.Fatalf("Expected ErrChallenge; Got %v", err)
+	}
+}
+
+func TestQueryRules(t *testing.T) {
+	challengeReq := []byte{0xff, 0xff, 0xff, 0xff, 'V', 0xff, 0xff, 0xff, 0xff}
+	rulesReq := []byte{0xff, 0xff, 0xff, 0xff, 'V', 0x4b, 0xa1, 0xd5, 0x22}
+	parts := splitPackets(7, rulesPacket, 3)
+	addr := serveUDP(t, func(req []byte) [][]byte {
+		switch {
+		case bytes.Equal(req, challengeReq):
+			return [][]byte{playersChallengePacket}
+		case bytes.Equal(req, rulesReq):
+			// out of order, with a duplicate
+			return [][]byte{parts[2], parts[0], parts[2], parts[1]}
+		default:
+			return [][]byte{{0xff, 0xff, 0xff, 0xff, '?'}}
+		}
+	})
+
+	rules, err := queryRules(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rules, expRules) {
+		t.Fatalf("Expected %+v; Got %+v", expRules, rules)
+	}
+}
@@ -0,0 +1,365 @@
+package steam
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amitybell/memio"
+	"github.com/amitybell/srcvox/demo"
+	"github.com/amitybell/srcvox/store"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// DefaultScanOptions are the options used for a Scanner's zero-valued options
+	DefaultScanOptions = ScanOptions{
+		Concurrency: 64,
+		Rate:        200,
+		Timeout:     3 * time.Second,
+		DeadTTL:     10 * time.Minute,
+	}
+)
+
+// ScanOptions bound the queries made by a Scanner
+type ScanOptions struct {
+	// Concurrency is the number of servers queried at once
+	Concurrency int
+	// Rate is the number of packets sent per second
+	Rate float64
+	// Timeout is how long to wait for a server to reply
+	Timeout time.Duration
+	// DeadTTL is how long a server that didn't reply is skipped for
+	DeadTTL time.Duration
+}
+
+func (o ScanOptions) withDefaults() ScanOptions {
+	d := DefaultScanOptions
+	if o.Concurrency <= 0 {
+		o.Concurrency = d.Concurrency
+	}
+	if o.Rate <= 0 {
+		o.Rate = d.Rate
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = d.Timeout
+	}
+	if o.DeadTTL == 0 {
+		o.DeadTTL = d.DeadTTL
+	}
+	return o
+}
+
+// Scanner queries the info of many servers over a single UDP socket
+type Scanner struct {
+	DB *store.DB
+	// MaxAge is how long cached server info is used for, instead of querying the server
+	MaxAge  time.Duration
+	Options ScanOptions
+}
+
+// scanJob is a query for a single server
+type scanJob struct {
+	addr     string
+	ap       netip.AddrPort
+	region   Region
+	sent     time.Time
+	deadline time.Time
+	// challenges is the number of challenges received
+	challenges int
+	challenge  int32
+	// resend is true if the reply to a challenge couldn't be queued, so the next sweep sends it
+	resend bool
+	split  splitReassembler
+}
+
+// scan is the state of a single call to Scanner.Scan
+type scan struct {
+	sc   *Scanner
+	opts ScanOptions
+	conn *net.UDPConn
+	fn   func(ServerInfo)
+
+	mu   sync.Mutex
+	jobs map[netip.AddrPort]*scanJob
+
+	sem      chan struct{}
+	resend   chan *scanJob
+	released chan struct{}
+}
+
+func deadServerKey(addr string) string {
+	return fmt.Sprintf("/serverInfo-dead/%s", addr)
+}
+
+// resolveAddr returns the address of the server at addr, which can be a hostname
+func resolveAddr(addr string) (netip.AddrPort, error) {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()), nil
+	}
+	ua, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("resolveAddr: %w", err)
+	}
+	ap := ua.AddrPort()
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()), nil
+}
+
+// Scan queries the info of servers, calling fn with each result as it arrives
+//
+// Servers with cached info are reported without querying them,
+// and servers that didn't reply recently are skipped.
+// fn is not called concurrently.
+func (sc *Scanner) Scan(ctx context.Context, servers map[string]Region, fn func(ServerInfo)) error {
+	maxAge := sc.MaxAge
+	if demo.Enabled {
+		maxAge = -1
+	}
+	opts := sc.Options.withDefaults()
+
+	addrs := make([]string, 0, len(servers))
+	for addr := range servers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	var queue []*scanJob
+	seen := map[netip.AddrPort]bool{}
+	for _, addr := range addrs {
+		if inf, ok := cachedServerInfo(sc.DB, maxAge, servers[addr], addr); ok {
+			fn(inf)
+			continue
+		}
+		ent, err := store.Get[store.CacheEntry[bool]](sc.DB, deadServerKey(addr))
+		if err == nil && ent.CheckTTL(opts.DeadTTL, 1) {
+			continue
+		}
+		ap, err := resolveAddr(addr)
+		if err != nil || seen[ap] {
+			continue
+		}
+		seen[ap] = true
+		queue = append(queue, &scanJob{addr: addr, ap: ap, region: servers[addr]})
+	}
+	if len(queue) == 0 {
+		return nil
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return fmt.Errorf("Scanner.Scan: %w", err)
+	}
+	defer conn.Close()
+
+	s := &scan{
+		sc:       sc,
+		opts:     opts,
+		conn:     conn,
+		fn:       fn,
+		jobs:     map[netip.AddrPort]*scanJob{},
+		sem:      make(chan struct{}, opts.Concurrency),
+		resend:   make(chan *scanJob, opts.Concurrency),
+		released: make(chan struct{}, 1),
+	}
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		s.readLoop()
+	}()
+	// make sure fn isn't called after we return
+	defer func() { conn.Close(); <-readDone }()
+
+	sweep := time.NewTicker(max(opts.Timeout/4, 10*time.Millisecond))
+	defer sweep.Stop()
+
+	lim := rate.NewLimiter(rate.Limit(opts.Rate), 1)
+	next := 0
+	for next < len(queue) || s.pending() > 0 {
+		var sem chan struct{}
+		if next < len(queue) {
+			sem = s.sem
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sweep.C:
+			for _, j := range s.sweep() {
+				if err := lim.Wait(ctx); err != nil {
+					return err
+				}
+				s.send(j)
+			}
+		case <-s.released:
+		case j := <-s.resend:
+			if err := lim.Wait(ctx); err != nil {
+				return err
+			}
+			s.send(j)
+		case sem <- struct{}{}:
+			if err := lim.Wait(ctx); err != nil {
+				return err
+			}
+			j := queue[next]
+			next++
+			s.mu.Lock()
+			s.jobs[j.ap] = j
+			s.mu.Unlock()
+			s.send(j)
+		}
+	}
+	return nil
+}
+
+func (s *scan) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.jobs)
+}
+
+func (s *scan) send(j *scanJob) {
+	s.mu.Lock()
+	j.resend = false
+	j.sent = time.Now()
+	j.deadline = j.sent.Add(s.opts.Timeout)
+	query := &ServerQuery{Header: 'T', Payload: "Source Engine Query", Challenge: j.challenge}
+	s.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	query.Encode(buf)
+	// failures are handled by the timeout
+	s.conn.WriteToUDPAddrPort(buf.Bytes(), j.ap)
+}
+
+// release removes job j, and returns true if it wasn't already removed
+func (s *scan) release(j *scanJob) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jobs[j.ap] != j {
+		return false
+	}
+	delete(s.jobs, j.ap)
+	<-s.sem
+	select {
+	case s.released <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *scan) fail(j *scanJob) {
+	if !s.release(j) {
+		return
+	}
+	// it's just a cache; it's fine if it fails
+	_ = store.Put(s.sc.DB, deadServerKey(j.addr), store.CacheEntry[bool]{Ts: time.Now().UTC(), V: true, Ver: 1})
+}
+
+// sweep fails the jobs that timed out, and returns the jobs whose challenge reply needs to be sent
+func (s *scan) sweep() []*scanJob {
+	now := time.Now()
+	var expired, resend []*scanJob
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		switch {
+		case j.resend:
+			resend = append(resend, j)
+		case now.After(j.deadline):
+			expired = append(expired, j)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, j := range expired {
+		s.fail(j)
+	}
+	return resend
+}
+
+func (s *scan) readLoop() {
+	p := make([]byte, 2<<10)
+	for {
+		n, from, err := s.conn.ReadFromUDPAddrPort(p)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			// e.g. ICMP port unreachable on Windows
+			continue
+		}
+		s.handle(netip.AddrPortFrom(from.Addr().Unmap(), from.Port()), p[:n])
+	}
+}
+
+func (s *scan) handle(from netip.AddrPort, pkt []byte) {
+	s.mu.Lock()
+	j := s.jobs[from]
+	s.mu.Unlock()
+	if j == nil {
+		return
+	}
+
+	if isSplitPacket(pkt) {
+		reply, err := j.split.Add(pkt)
+		if err != nil {
+			s.fail(j)
+			return
+		}
+		if reply == nil {
+			return
+		}
+		pkt = reply
+	}
+
+	rep := &ServerReply{Ts: time.Now()}
+	err := rep.Decode(memio.NewFile(pkt))
+	switch {
+	case err == nil:
+		s.mu.Lock()
+		rep.Ping = rep.Ts.Sub(j.sent)
+		s.mu.Unlock()
+		if !s.release(j) {
+			return
+		}
+		rep.Region = j.region
+		_ = store.Put(s.sc.DB, serverInfoKey(j.addr), store.CacheEntry[*ServerReply]{Ts: time.Now().UTC(), V: rep, Ver: serverInfoVer})
+		s.fn(newServerInfo(j.region, j.addr, rep))
+	case errors.Is(err, ErrChallenge):
+		s.mu.Lock()
+		j.challenges++
+		j.challenge = rep.Challenge
+		j.split = splitReassembler{}
+		ok := j.challenges <= 3
+		s.mu.Unlock()
+		if !ok {
+			s.fail(j)
+			return
+		}
+		select {
+		case s.resend <- j:
+		default:
+			// the server is alive, so let the next sweep send it instead of timing out
+			s.mu.Lock()
+			j.resend = true
+			s.mu.Unlock()
+		}
+	default:
+		s.fail(j)
+	}
+}
+
+// cachedServerInfo returns the cached info for the server at addr, if it's newer than maxAge
+func cachedServerInfo(db *store.DB, maxAge time.Duration, region Region, addr string) (ServerInfo, bool) {
+	ent, err := store.Get[store.CacheEntry[*ServerReply]](db, serverInfoKey(addr))
+	if err != nil || ent.V == nil || !ent.CheckTTL(maxAge, serverInfoVer) {
+		return ServerInfo{}, false
+	}
+	return newServerInfo(region, addr, ent.V), true
+}
@@ -0,0 +1,153 @@
+package steam
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+func TestScanner(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	info, err := os.ReadFile(filepath.Join("testdata", "info-tf2.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := playersChallengePacket
+	query := func(challenge bool) []byte {
+		q := append([]byte{0xff, 0xff, 0xff, 0xff, 'T'}, "Source Engine Query\x00"...)
+		if challenge {
+			q = append(q, playersChallengePacket[5:]...)
+		}
+		return q
+	}
+
+	mu := sync.Mutex{}
+	clients := map[string]bool{}
+	requests := map[string]int{}
+	server := func(name string, reply func(req []byte) [][]byte) string {
+		return serveUDPFrom(t, func(from *net.UDPAddr, req []byte) [][]byte {
+			mu.Lock()
+			clients[from.String()] = true
+			requests[name]++
+			mu.Unlock()
+			return reply(req)
+		})
+	}
+	servers := map[string]Region{
+		// replies after a challenge
+		server("challenge", func(req []byte) [][]byte {
+			if bytes.Equal(req, query(true)) {
+				return [][]byte{info}
+			}
+			return [][]byte{challenge}
+		}): Europe,
+		// replies immediately
+		server("direct", func(req []byte) [][]byte {
+			return [][]byte{info}
+		}): Asia,
+		// never replies
+		server("dead", func(req []byte) [][]byte {
+			return nil
+		}): Africa,
+	}
+
+	sc := &Scanner{
+		DB:      db,
+		MaxAge:  time.Minute,
+		Options: ScanOptions{Concurrency: 2, Timeout: 200 * time.Millisecond},
+	}
+	scan := func() []ServerInfo {
+		var l []ServerInfo
+		if err := sc.Scan(context.Background(), servers, func(inf ServerInfo) { l = append(l, inf) }); err != nil {
+			t.Fatal(err)
+		}
+		sort.Slice(l, func(i, j int) bool { return l[i].Region < l[j].Region })
+		return l
+	}
+
+	l := scan()
+	if len(l) != 2 || l[0].Region != Europe || l[1].Region != Asia || l[0].Name != "Uncletopia | Seattle | 1" {
+		t.Fatalf("Expected info for 2 servers; Got %+v", l)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(clients) != 1 {
+		t.Fatalf("Expected queries to be sent from a single socket; Got %d", len(clients))
+	}
+	if requests["challenge"] != 2 || requests["direct"] != 1 || requests["dead"] != 1 {
+		t.Fatalf("Expected 2 requests to the challenge server and 1 to the others; Got %v", requests)
+	}
+
+	// cached info is reported without a query, and the dead server is skipped
+	mu.Unlock()
+	l = scan()
+	mu.Lock()
+	if len(l) != 2 {
+		t.Fatalf("Expected cached info for 2 servers; Got %+v", l)
+	}
+	if requests["challenge"] != 2 || requests["direct"] != 1 || requests["dead"] != 1 {
+		t.Fatalf("Expected no new requests; Got %v", requests)
+	}
+}
+
+func TestScannerCancel(t *testing.T) {
+	addr := serveUDP(t, func(req []byte) [][]byte { return nil })
+	sc := &Scanner{Options: ScanOptions{Timeout: time.Minute}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := sc.Scan(ctx, map[string]Region{addr: Europe}, func(ServerInfo) {})
+	if err == nil {
+		t.Fatal("Expected a cancelled scan to fail")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the scan to stop when cancelled; Took %s", d)
+	}
+}
+
+func TestScannerResendFull(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// the resend queue is always full
+	s := &scan{
+		sc:       &Scanner{DB: db},
+		opts:     ScanOptions{Timeout: time.Millisecond},
+		jobs:     map[netip.AddrPort]*scanJob{},
+		sem:      make(chan struct{}, 1),
+		resend:   make(chan *scanJob),
+		released: make(chan struct{}, 1),
+	}
+	j := &scanJob{addr: "127.0.0.1:27015", ap: netip.MustParseAddrPort("127.0.0.1:27015"), deadline: time.Now().Add(-time.Second)}
+	s.sem <- struct{}{}
+	s.jobs[j.ap] = j
+
+	s.handle(j.ap, playersChallengePacket)
+	if l := s.sweep(); len(l) != 1 || l[0] != j {
+		t.Fatalf("Expected the sweep to return the challenged job; Got %v", l)
+	}
+	if s.pending() != 1 {
+		t.Fatal("Expected the challenged job not to fail")
+	}
+	if _, err := store.Get[store.CacheEntry[bool]](db, deadServerKey(j.addr)); err == nil {
+		t.Fatal("Expected the challenged server not to be marked dead")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"net"
 	"net/netip"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -53,6 +55,7 @@ const (
 	Australia      Region = 0x05
 	MiddleEast     Region = 0x06
 	Africa         Region = 0x07
+	LAN            Region = 0xFE
 	RestOfTheworld Region = 0xFF
 )
 
@@ -74,6 +77,8 @@ func (r Region) String() string {
 		return "middle-east"
 	case Africa:
 		return "africa"
+	case LAN:
+		return "lan"
 	default:
 		return "rest-of-world"
 	}
@@ -92,6 +97,14 @@ type ServerInfo struct {
 	Region     Region    `json:"region"`
 	Country    string    `json:"country"`
 	Ts         time.Time `json:"ts"`
+
+	AppID   int      `json:"appID"`
+	Version string   `json:"version"`
+	Tags    []string `json:"tags"`
+	// SteamID is the server's 64-bit SteamID, as a string because it doesn't fit in a JS number
+	SteamID      string `json:"steamID"`
+	SourceTVPort int    `json:"sourceTVPort"`
+	SourceTVName string `json:"sourceTVName"`
 }
 
 type MasterQuery struct {
@@ -156,8 +169,22 @@ func dialUDP(addr string) (*net.UDPConn, error) {
 	return c.(*net.UDPConn), nil
 }
 
+// readMsgUDP reads a reply into buf, joining it if it's split over multiple packets
 func readMsgUDP(conn *net.UDPConn, buf *memio.File) error {
-	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if err := readPacketUDP(conn, buf, time.Now().Add(10*time.Second)); err != nil {
+		return fmt.Errorf("readMsgUDP: %w", err)
+	}
+	if p := buf.Bytes(); isSplitPacket(p) {
+		if err := readSplitUDP(conn, buf, p); err != nil {
+			return fmt.Errorf("readMsgUDP: %w", err)
+		}
+	}
+	return nil
+}
+
+// readPacketUDP reads a single packet into buf, waiting until deadline
+func readPacketUDP(conn *net.UDPConn, buf *memio.File, deadline time.Time) error {
+	conn.SetReadDeadline(deadline)
 
 	const minBfSize = 2 << 10
 	s := buf.Bytes()
@@ -166,14 +193,14 @@ func readMsgUDP(conn *net.UDPConn, buf *memio.File) error {
 	}
 	n, _, err := conn.ReadFromUDP(s)
 	if err != nil {
-		return fmt.Errorf("readMsgUDP: %w", err)
+		return fmt.Errorf("readPacketUDP: %w", err)
 	}
 	buf.Reset(s[:n])
 	return nil
 }
 
-func queryRegionServerList(gameID ID, region Region) ([]*MasterReply, error) {
-	conn, err := dialUDP(SourceMasterServerAddr)
+func queryRegionServerList(master string, gameID ID, region Region, filter Filter) ([]*MasterReply, error) {
+	conn, err := dialUDP(master)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +210,7 @@ func queryRegionServerList(gameID ID, region Region) ([]*MasterReply, error) {
 		Header: '1',
 		Addr:   "0.0.0.0:0",
 		Region: region,
-		Filter: fmt.Sprintf(`\appid\%d`, gameID.To32()),
+		Filter: filter.Query(gameID),
 	}
 
 	if err := mq.Encode(conn); err != nil {
@@ -202,12 +229,9 @@ func queryRegionServerList(gameID ID, region Region) ([]*MasterReply, error) {
 		}
 
 		var last *MasterReply
-		for {
+		for int(buf.Offset()) < buf.Len() {
 			reply := &MasterReply{Region: region}
 			if err := reply.Decode(buf); err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
 				return replies, err
 			}
 			if reply.ip == masterServerLastIP {
@@ -228,7 +252,7 @@ func queryRegionServerList(gameID ID, region Region) ([]*MasterReply, error) {
 	}
 }
 
-func queryServerList(gameID ID) ([][]*MasterReply, error) {
+func queryServerList(master string, gameID ID, filter Filter) ([][]*MasterReply, error) {
 	replies := make([][]*MasterReply, len(regions))
 
 	wg := sync.WaitGroup{}
@@ -236,7 +260,7 @@ func queryServerList(gameID ID) ([][]*MasterReply, error) {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			replies[i], _ = queryRegionServerList(gameID, regions[i])
+			replies[i], _ = queryRegionServerList(master, gameID, regions[i], filter)
 		}(i)
 	}
 	wg.Wait()
@@ -244,13 +268,14 @@ func queryServerList(gameID ID) ([][]*MasterReply, error) {
 	return replies, nil
 }
 
-func QueryServerList(db *store.DB, maxAge time.Duration, gameID ID) (map[string]Region, error) {
+// QueryServerList returns the address and region of the servers for the game that match filter
+func QueryServerList(db *store.DB, maxAge time.Duration, gameID ID, filter Filter) (map[string]Region, error) {
 	if demo.Enabled {
 		maxAge = -1
 	}
-	key := fmt.Sprintf("/serverList/addr-region/%d", gameID.To32())
+	key := fmt.Sprintf("/serverList/addr-region/%s", filter.Query(gameID))
 	m, err := store.CacheTTL(db, maxAge, key, 2, func() (map[string]Region, error) {
-		replies, err := queryServerList(gameID)
+		replies, err := queryServerList(SourceMasterServerAddr, gameID, filter)
 		if err != nil {
 			return nil, fmt.Errorf("serverList: %s", err)
 		}
@@ -272,6 +297,17 @@ func QueryServerList(db *store.DB, maxAge time.Duration, gameID ID) (map[string]
 	return m, nil
 }
 
+const (
+	theShipAppID = 2400
+
+	// Extra Data Flags
+	edfPort     = 0x80
+	edfSteamID  = 0x10
+	edfSourceTV = 0x40
+	edfKeywords = 0x20
+	edfGameID   = 0x01
+)
+
 type ServerQuery struct {
 	Header    byte
 	Payload   string
@@ -317,8 +353,8 @@ type ServerReply struct {
 	// Full name of the game.
 	Game string
 
-	// Steam Application ID of game.
-	ID int16
+	// Steam Application ID of game, truncated to 16 bits; see AppID
+	ID uint16
 
 	// 	Number of players on the server.
 	Players byte
@@ -351,8 +387,38 @@ type ServerReply struct {
 	// 1 for secured
 	VAC byte
 
+	// The Ship only: the game mode, number of witnesses needed to arrest a player,
+	// and time in seconds before a player is arrested while being witnessed.
+	Mode      byte
+	Witnesses byte
+	Duration  byte
+
+	// Version of the game installed on the server.
+	Version string
+
+	// Extra Data Flag: which of the following fields are present
+	EDF byte
+
+	// The server's game port number.
+	Port uint16
+
+	// Server's SteamID.
+	SteamID uint64
+
+	// Spectator port number and name of the spectator server for SourceTV.
+	SourceTVPort uint16
+	SourceTVName string
+
+	// Tags that describe the game according to the server.
+	Keywords string
+
+	// The server's 64-bit GameID. The low 24 bits are the appid.
+	GameID uint64
+
 	Ping      time.Duration
 	Challenge int32
+	// Region is the region the server was queried for, it's not part of the reply
+	Region Region
 
 	Ts time.Time
 }
@@ -393,7 +459,7 @@ func (sr *ServerReply) decodeInfo(r *memio.File) error {
 		return fmt.Errorf("ServerReply.Decode: Game: %w", err)
 	}
 
-	sr.ID, err = r.ReadInt16(binary.LittleEndian)
+	sr.ID, err = r.ReadUint16(binary.LittleEndian)
 	if err != nil {
 		return fmt.Errorf("ServerReply.Decode: ID: %w", err)
 	}
@@ -433,9 +499,116 @@ func (sr *ServerReply) decodeInfo(r *memio.File) error {
 		return fmt.Errorf("ServerReply.Decode: VAC: %w", err)
 	}
 
+	if sr.ID == theShipAppID {
+		if err := sr.decodeTheShip(r); err != nil {
+			return err
+		}
+	}
+
+	// some older servers end the reply here
+	if int(r.Offset()) >= r.Len() {
+		return nil
+	}
+
+	sr.Version, err = r.ReadString(0)
+	if err != nil {
+		return fmt.Errorf("ServerReply.Decode: Version: %w", err)
+	}
+
+	if int(r.Offset()) >= r.Len() {
+		return nil
+	}
+	return sr.decodeEDF(r)
+}
+
+func (sr *ServerReply) decodeTheShip(r *memio.File) error {
+	var err error
+	sr.Mode, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("ServerReply.Decode: Mode: %w", err)
+	}
+
+	sr.Witnesses, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("ServerReply.Decode: Witnesses: %w", err)
+	}
+
+	sr.Duration, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("ServerReply.Decode: Duration: %w", err)
+	}
+	return nil
+}
+
+func (sr *ServerReply) decodeEDF(r *memio.File) error {
+	var err error
+	sr.EDF, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("ServerReply.Decode: EDF: %w", err)
+	}
+
+	if sr.EDF&edfPort != 0 {
+		sr.Port, err = r.ReadUint16(binary.LittleEndian)
+		if err != nil {
+			return fmt.Errorf("ServerReply.Decode: Port: %w", err)
+		}
+	}
+
+	if sr.EDF&edfSteamID != 0 {
+		sr.SteamID, err = r.ReadUint64(binary.LittleEndian)
+		if err != nil {
+			return fmt.Errorf("ServerReply.Decode: SteamID: %w", err)
+		}
+	}
+
+	if sr.EDF&edfSourceTV != 0 {
+		sr.SourceTVPort, err = r.ReadUint16(binary.LittleEndian)
+		if err != nil {
+			return fmt.Errorf("ServerReply.Decode: SourceTVPort: %w", err)
+		}
+
+		sr.SourceTVName, err = r.ReadString(0)
+		if err != nil {
+			return fmt.Errorf("ServerReply.Decode: SourceTVName: %w", err)
+		}
+	}
+
+	if sr.EDF&edfKeywords != 0 {
+		sr.Keywords, err = r.ReadString(0)
+		if err != nil {
+			return fmt.Errorf("ServerReply.Decode: Keywords: %w", err)
+		}
+	}
+
+	if sr.EDF&edfGameID != 0 {
+		sr.GameID, err = r.ReadUint64(binary.LittleEndian)
+		if err != nil {
+			return fmt.Errorf("ServerReply.Decode: GameID: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// AppID returns the server's appid, from GameID if it's set
+func (sr *ServerReply) AppID() uint32 {
+	if sr.EDF&edfGameID != 0 {
+		return uint32(sr.GameID & 0xffffff)
+	}
+	return uint32(sr.ID)
+}
+
+// Tags returns the non-empty tags in Keywords
+func (sr *ServerReply) Tags() []string {
+	var tags []string
+	for _, s := range strings.Split(sr.Keywords, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
 func (sr *ServerReply) Decode(r *memio.File) error {
 	pfx, err := r.ReadInt32(binary.LittleEndian)
 	if err != nil {
@@ -490,6 +663,7 @@ func queryServerInfo(region Region, addr string) (*ServerReply, error) {
 		switch {
 		case err == nil:
 			reply.Ping = ping
+			reply.Region = region
 			return reply, nil
 		case errors.Is(err, ErrChallenge):
 			challenge = reply.Challenge
@@ -499,18 +673,7 @@ func queryServerInfo(region Region, addr string) (*ServerReply, error) {
 	}
 }
 
-func QueryServerInfo(db *store.DB, maxAge time.Duration, region Region, addr string) (ServerInfo, *ServerReply, error) {
-	if demo.Enabled {
-		maxAge = -1
-	}
-
-	key := fmt.Sprintf("/serverInfo/%s", addr)
-	rep, err := store.CacheTTL(db, maxAge, key, 2, func() (*ServerReply, error) {
-		return queryServerInfo(region, addr)
-	})
-	if err != nil && (!errors.Is(err, store.ErrStale) || rep == nil) {
-		return ServerInfo{}, nil, err
-	}
+func newServerInfo(region Region, addr string, rep *ServerReply) ServerInfo {
 	ip, _, _ := net.SplitHostPort(addr)
 	cc, _ := ip2country.LookupString(ip)
 	inf := ServerInfo{
@@ -526,11 +689,50 @@ func QueryServerInfo(db *store.DB, maxAge time.Duration, region Region, addr str
 		Region:     region,
 		Country:    cc,
 		Ts:         rep.Ts,
+
+		AppID:        int(rep.AppID()),
+		Version:      rep.Version,
+		Tags:         rep.Tags(),
+		SourceTVPort: int(rep.SourceTVPort),
+		SourceTVName: rep.SourceTVName,
+	}
+	if rep.SteamID != 0 {
+		inf.SteamID = strconv.FormatUint(rep.SteamID, 10)
 	}
 	if demo.Enabled {
 		inf.MaxPlayers = 32
 		inf.Players = inf.MaxPlayers - rng.Intn(4)
 		inf.Bots = inf.MaxPlayers - inf.Players
 	}
-	return inf, rep, nil
+	return inf
+}
+
+// serverInfoVer is the version of the cached ServerReply
+const serverInfoVer = 4
+
+func serverInfoKey(addr string) string {
+	return fmt.Sprintf("/serverInfo/%s", addr)
+}
+
+func QueryServerInfo(db *store.DB, maxAge time.Duration, region Region, addr string) (ServerInfo, *ServerReply, error) {
+	if demo.Enabled {
+		maxAge = -1
+	}
+
+	rep, err := store.CacheTTL(db, maxAge, serverInfoKey(addr), serverInfoVer, func() (*ServerReply, error) {
+		return queryServerInfo(region, addr)
+	})
+	if err != nil && (!errors.Is(err, store.ErrStale) || rep == nil) {
+		return ServerInfo{}, nil, err
+	}
+	return newServerInfo(region, addr, rep), rep, nil
+}
+
+// CachedServerInfo returns the last known info for the server at addr, without querying the server
+func CachedServerInfo(db *store.DB, addr string) (ServerInfo, bool) {
+	ent, err := store.Get[store.CacheEntry[*ServerReply]](db, serverInfoKey(addr))
+	if err != nil || ent.V == nil {
+		return ServerInfo{}, false
+	}
+	return newServerInfo(ent.V.Region, addr, ent.V), true
 }
@@ -0,0 +1,71 @@
+package steam
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/amitybell/memio"
+)
+
+func TestServerReplyDecode(t *testing.T) {
+	cases := map[string]ServerInfo{
+		"tf2": {
+			Name: "Uncletopia | Seattle | 1", Map: "pl_upward", Game: "Team Fortress",
+			Players: 23, MaxPlayers: 24, AppID: 440, Version: "8604597",
+			Tags:    []string{"alltalk", "increased_maxplayers", "nocrits", "payload"},
+			SteamID: "85568392924039655",
+		},
+		"csgo": {
+			Name: "Valve CS:GO Washington Server", Map: "de_dust2", Game: "Counter-Strike: Global Offensive",
+			Players: 10, MaxPlayers: 10, AppID: 730, Version: "1.38.7.9",
+			Tags:         []string{"valve_ds", "empty", "secure"},
+			SteamID:      "90154447654797313",
+			SourceTVPort: 27020, SourceTVName: "GOTV",
+		},
+		"theship": {
+			Name: "The Ship Server", Map: "batavier", Game: "The Ship",
+			Players: 4, MaxPlayers: 16, Bots: 2, AppID: 2400, Version: "1.0.0.4",
+		},
+		"mcv": {
+			Name: "MCV | US East", Map: "dong_hai", Game: "Military Conflict: Vietnam",
+			Players: 31, MaxPlayers: 64, Restricted: true, AppID: 1012110, Version: "1.0.0.0",
+			Tags:    []string{"hidden"},
+			SteamID: "90176120129994763",
+		},
+	}
+	for name, exp := range cases {
+		p, err := os.ReadFile(filepath.Join("testdata", "info-"+name+".bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep := &ServerReply{}
+		if err := rep.Decode(memio.NewFile(p)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		inf := newServerInfo(0, "", rep)
+		inf.Country = ""
+		if !reflect.DeepEqual(inf, exp) {
+			t.Fatalf("%s: Expected %+v; Got %+v", name, exp, inf)
+		}
+		if name == "theship" && (rep.Mode != 1 || rep.Witnesses != 3 || rep.Duration != 15 || rep.Port != 27015) {
+			t.Fatalf("%s: Expected The Ship fields to be decoded; Got %+v", name, rep)
+		}
+
+		if err := (&ServerReply{}).Decode(memio.NewFile(p[:len(p)-1])); err == nil {
+			t.Fatalf("%s: Expected truncated reply to fail", name)
+		}
+	}
+
+	// older servers end the reply after VAC
+	old := append([]byte{0xff, 0xff, 0xff, 0xff, 'I', 7}, "old\x00dod_anzio\x00dod\x00Day of Defeat: Source\x00"...)
+	old = append(old, 0x2c, 0x01, 1, 32, 0, 'd', 'w', 0, 1)
+	rep := &ServerReply{}
+	if err := rep.Decode(memio.NewFile(old)); err != nil {
+		t.Fatal(err)
+	}
+	if rep.AppID() != 300 || rep.Version != "" || rep.EDF != 0 {
+		t.Fatalf("Expected appid 300 without version or EDF; Got %+v", rep)
+	}
+}
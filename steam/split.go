@@ -0,0 +1,275 @@
+package steam
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"time"
+
+	"github.com/amitybell/memio"
+)
+
+const (
+	// maxSplitReplySize is the largest reply we'll join or decompress
+	maxSplitReplySize = 1 << 20
+
+	// maxSplitPending is the number of packets kept while the format of a split reply is unknown
+	maxSplitPending = 64
+)
+
+var (
+	// splitTimeout is how long to wait for the remaining packets of a split reply
+	splitTimeout = 5 * time.Second
+
+	ErrSplitPacket = errors.New("Invalid split packet")
+
+	singlePacketPrefix = []byte{0xff, 0xff, 0xff, 0xff}
+)
+
+// splitFormat is the header format of a split packet
+type splitFormat int
+
+const (
+	splitUnknown splitFormat = iota
+	// splitSource is the Source engine format, with an optional bzip2 compressed payload
+	splitSource
+	// splitSourceLegacy is the Source engine format without the Size field, used by some older games
+	splitSourceLegacy
+	// splitGoldSrc is the GoldSrc format, with the packet number and total packed into one byte
+	splitGoldSrc
+)
+
+// isSplitPacket reports whether p has the split packet prefix (-2)
+func isSplitPacket(p []byte) bool {
+	return len(p) >= 4 && int32(binary.LittleEndian.Uint32(p)) == -2
+}
+
+// detectSplitFormat returns the format of p if it's the first packet of a split reply
+//
+// The formats can't be told apart from the other packets, but the payload of the first packet
+// always starts with the single packet prefix (-1), or the bzip2 magic if it's compressed.
+func detectSplitFormat(p []byte) splitFormat {
+	if len(p) < 13 {
+		return splitUnknown
+	}
+	id := binary.LittleEndian.Uint32(p[4:])
+	switch {
+	case p[8]>>4 == 0 && p[8] != 0 && bytes.HasPrefix(p[9:], singlePacketPrefix):
+		return splitGoldSrc
+	case p[8] != 0 && p[9] == 0 && bytes.HasPrefix(p[10:], singlePacketPrefix):
+		return splitSourceLegacy
+	case p[8] != 0 && p[9] == 0 && id&0x80000000 == 0 && bytes.HasPrefix(p[12:], singlePacketPrefix):
+		return splitSource
+	case p[8] != 0 && p[9] == 0 && id&0x80000000 != 0 && len(p) >= 20 && bytes.HasPrefix(p[20:], []byte("BZh")):
+		return splitSource
+	default:
+		return splitUnknown
+	}
+}
+
+// splitPacket is one packet of a reply that's split over multiple packets
+type splitPacket struct {
+	ID     int32
+	Total  byte
+	Number byte
+	// Compressed is true if the joined payload is bzip2 compressed
+	Compressed bool
+	// Size and CRC are the size and CRC32 of the decompressed reply, set on the first packet of a compressed reply
+	Size int32
+	CRC  uint32
+	// Payload is this packet's part of the reply
+	Payload []byte
+}
+
+func (sp *splitPacket) Decode(r *memio.File, format splitFormat) error {
+	pfx, err := r.ReadInt32(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("splitPacket.Decode: Pfx: %w", err)
+	}
+	if pfx != -2 {
+		return fmt.Errorf("splitPacket.Decode: %w: %v", ErrUnknownPrefix, pfx)
+	}
+
+	sp.ID, err = r.ReadInt32(binary.LittleEndian)
+	if err != nil {
+		return fmt.Errorf("splitPacket.Decode: ID: %w", err)
+	}
+
+	switch format {
+	case splitGoldSrc:
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("splitPacket.Decode: Number: %w", err)
+		}
+		sp.Number, sp.Total = b>>4, b&0x0f
+	case splitSource, splitSourceLegacy:
+		sp.Total, err = r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("splitPacket.Decode: Total: %w", err)
+		}
+		sp.Number, err = r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("splitPacket.Decode: Number: %w", err)
+		}
+	default:
+		return fmt.Errorf("splitPacket.Decode: %w: unknown format", ErrSplitPacket)
+	}
+	if sp.Total == 0 || sp.Number >= sp.Total {
+		return fmt.Errorf("splitPacket.Decode: %w: packet %d of %d", ErrSplitPacket, sp.Number, sp.Total)
+	}
+
+	if format == splitSource {
+		if _, err := r.ReadInt16(binary.LittleEndian); err != nil {
+			return fmt.Errorf("splitPacket.Decode: Size: %w", err)
+		}
+		sp.Compressed = uint32(sp.ID)&0x80000000 != 0
+		if sp.Compressed && sp.Number == 0 {
+			sp.Size, err = r.ReadInt32(binary.LittleEndian)
+			if err != nil {
+				return fmt.Errorf("splitPacket.Decode: Decompressed Size: %w", err)
+			}
+			sp.CRC, err = r.ReadUint32(binary.LittleEndian)
+			if err != nil {
+				return fmt.Errorf("splitPacket.Decode: CRC: %w", err)
+			}
+		}
+	}
+
+	sp.Payload = r.Bytes()[r.Offset():]
+	return nil
+}
+
+// splitReassembler joins the packets of a split reply
+//
+// Packets can arrive in any order, duplicates are ignored,
+// and packets from other replies (e.g. a late reply to an earlier query) are skipped.
+type splitReassembler struct {
+	id      int32
+	started bool
+	format  splitFormat
+	// pending are the packets received before the format is known
+	pending [][]byte
+	first   *splitPacket
+	parts   [][]byte
+	n       int
+	size    int
+}
+
+// Add adds packet p to the reply
+//
+// It returns the joined reply once all packets have been added.
+func (sr *splitReassembler) Add(p []byte) ([]byte, error) {
+	if !isSplitPacket(p) || len(p) < 9 {
+		// single packets and fragments aren't part of this reply
+		return nil, nil
+	}
+	id := int32(binary.LittleEndian.Uint32(p[4:]))
+	if !sr.started {
+		sr.started = true
+		sr.id = id
+	}
+	if id != sr.id {
+		return nil, nil
+	}
+
+	p = append([]byte(nil), p...)
+	if sr.format == splitUnknown {
+		sr.format = detectSplitFormat(p)
+	}
+	if sr.format == splitUnknown {
+		if len(sr.pending) >= maxSplitPending {
+			return nil, fmt.Errorf("splitReassembler.Add: %w: no first packet", ErrSplitPacket)
+		}
+		sr.pending = append(sr.pending, p)
+		return nil, nil
+	}
+
+	pending := append(sr.pending, p)
+	sr.pending = nil
+	for _, p := range pending {
+		reply, err := sr.add(p)
+		if reply != nil || err != nil {
+			return reply, err
+		}
+	}
+	return nil, nil
+}
+
+func (sr *splitReassembler) add(p []byte) ([]byte, error) {
+	sp := &splitPacket{}
+	if err := sp.Decode(memio.NewFile(p), sr.format); err != nil {
+		return nil, fmt.Errorf("splitReassembler.Add: %w", err)
+	}
+	if sr.parts == nil {
+		sr.parts = make([][]byte, sp.Total)
+	}
+	if int(sp.Total) != len(sr.parts) {
+		return nil, fmt.Errorf("splitReassembler.Add: %w: packet %d of %d in a reply of %d", ErrSplitPacket, sp.Number, sp.Total, len(sr.parts))
+	}
+	if sr.parts[sp.Number] != nil {
+		return nil, nil
+	}
+
+	sr.size += len(sp.Payload)
+	if sr.size > maxSplitReplySize {
+		return nil, fmt.Errorf("splitReassembler.Add: %w: reply is too large", ErrSplitPacket)
+	}
+	sr.parts[sp.Number] = sp.Payload
+	if sp.Number == 0 {
+		sr.first = sp
+	}
+	sr.n++
+	if sr.n < len(sr.parts) {
+		return nil, nil
+	}
+
+	reply := make([]byte, 0, sr.size)
+	for _, p := range sr.parts {
+		reply = append(reply, p...)
+	}
+	if sr.first.Compressed {
+		return decompressSplitReply(reply, sr.first.Size, sr.first.CRC)
+	}
+	return reply, nil
+}
+
+func decompressSplitReply(p []byte, size int32, crc uint32) ([]byte, error) {
+	if size < 0 || size > maxSplitReplySize {
+		return nil, fmt.Errorf("decompressSplitReply: %w: invalid size %d", ErrSplitPacket, size)
+	}
+	reply := make([]byte, size)
+	if _, err := io.ReadFull(bzip2.NewReader(bytes.NewReader(p)), reply); err != nil {
+		return nil, fmt.Errorf("decompressSplitReply: %w", err)
+	}
+	if c := crc32.ChecksumIEEE(reply); c != crc {
+		return nil, fmt.Errorf("decompressSplitReply: %w: CRC %08x, expected %08x", ErrSplitPacket, c, crc)
+	}
+	return reply, nil
+}
+
+// readSplitUDP reads the remaining packets of the split reply that starts with packet first,
+// and resets buf to the joined reply
+func readSplitUDP(conn *net.UDPConn, buf *memio.File, first []byte) error {
+	deadline := time.Now().Add(splitTimeout)
+	sr := &splitReassembler{}
+	p := first
+	for {
+		reply, err := sr.Add(p)
+		if err != nil {
+			return fmt.Errorf("readSplitUDP: %w", err)
+		}
+		if reply != nil {
+			buf.Reset(reply)
+			return nil
+		}
+		if err := readPacketUDP(conn, buf, deadline); err != nil {
+			return fmt.Errorf("readSplitUDP: %w", err)
+		}
+		p = buf.Bytes()
+	}
+}
@@ -0,0 +1,170 @@
+package steam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amitybell/memio"
+)
+
+// readPackets returns the packets in testdata/<name>-N.bin
+func readPackets(t testing.TB, name string) [][]byte {
+	var l [][]byte
+	for i := 0; ; i++ {
+		p, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("%s-%d.bin", name, i)))
+		if os.IsNotExist(err) && i > 0 {
+			return l
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		l = append(l, p)
+	}
+}
+
+// permutations returns every order of [0, n)
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{nil}
+	}
+	var l [][]int
+	for _, p := range permutations(n - 1) {
+		for i := 0; i <= len(p); i++ {
+			q := append(append(append([]int(nil), p[:i]...), n-1), p[i:]...)
+			l = append(l, q)
+		}
+	}
+	return l
+}
+
+func TestSplitReassembler(t *testing.T) {
+	exp, err := os.ReadFile(filepath.Join("testdata", "rules.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"source", "source-bz2", "legacy", "goldsrc"} {
+		packets := readPackets(t, name)
+		for _, order := range permutations(len(packets)) {
+			sr := &splitReassembler{}
+			var reply []byte
+			for i, j := range order {
+				// every packet is sent twice
+				for k := 0; k < 2; k++ {
+					r, err := sr.Add(packets[j])
+					if err != nil {
+						t.Fatalf("%s %v: %v", name, order, err)
+					}
+					if r != nil && (reply != nil || i != len(order)-1) {
+						t.Fatalf("%s %v: Reply returned before the last packet, or more than once", name, order)
+					}
+					if r != nil {
+						reply = r
+					}
+				}
+			}
+			if !bytes.Equal(reply, exp) {
+				t.Fatalf("%s %v: Expected reply %q; Got %q", name, order, exp, reply)
+			}
+		}
+	}
+
+	rep := &RulesReply{}
+	if err := rep.Decode(memio.NewFile(exp)); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Rules["sm_nextmap"] != "dod_anzio" {
+		t.Fatalf("Expected sm_nextmap=dod_anzio; Got %+v", rep.Rules)
+	}
+}
+
+func TestSplitReassemblerErrors(t *testing.T) {
+	source := readPackets(t, "source")
+	goldsrc := readPackets(t, "goldsrc")
+
+	// packets from other replies are skipped
+	sr := &splitReassembler{}
+	for _, p := range [][]byte{source[1], goldsrc[0], singlePacketPrefix, source[0], source[2]} {
+		if _, err := sr.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sr.n != len(source) {
+		t.Fatalf("Expected %d packets; Got %d", len(source), sr.n)
+	}
+
+	// the packet count must match
+	bad := append([]byte(nil), source[1]...)
+	bad[8] = 2
+	sr = &splitReassembler{}
+	sr.Add(source[0])
+	if _, err := sr.Add(bad); !errors.Is(err, ErrSplitPacket) {
+		t.Fatalf("Expected ErrSplitPacket; Got %v", err)
+	}
+
+	// the decompressed reply must match the CRC
+	bz2 := readPackets(t, "source-bz2")
+	bad = append([]byte(nil), bz2[0]...)
+	bad[16] ^= 0xff
+	sr = &splitReassembler{}
+	sr.Add(bad)
+	if _, err := sr.Add(bz2[1]); !errors.Is(err, ErrSplitPacket) {
+		t.Fatalf("Expected ErrSplitPacket; Got %v", err)
+	}
+}
+
+func TestReadSplitTimeout(t *testing.T) {
+	defer func(d time.Duration) { splitTimeout = d }(splitTimeout)
+	splitTimeout = 200 * time.Millisecond
+
+	source := readPackets(t, "source")
+	addr := serveUDP(t, func(req []byte) [][]byte {
+		// the last packet is lost
+		return source[:2]
+	})
+	conn, err := dialUDP(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	conn.Write([]byte{0})
+	err = readMsgUDP(conn, memio.NewFile(nil))
+	if err == nil {
+		t.Fatal("Expected an incomplete reply to fail")
+	}
+	if d := time.Since(start); d > splitTimeout+time.Second {
+		t.Fatalf("Expected to give up after %s; Took %s", splitTimeout, d)
+	}
+}
+
+func FuzzSplitReassembler(f *testing.F) {
+	for _, name := range []string{"source", "source-bz2", "legacy", "goldsrc"} {
+		packets := readPackets(f, name)
+		f.Add(packets[0], packets[len(packets)-1])
+	}
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		sr := &splitReassembler{}
+		for _, p := range [][]byte{a, b, a} {
+			reply, err := sr.Add(p)
+			if err != nil {
+				return
+			}
+			if reply != nil {
+				// the decoders must cope with whatever is joined
+				(&ServerReply{}).Decode(memio.NewFile(reply))
+				(&PlayersReply{}).Decode(memio.NewFile(reply))
+				(&RulesReply{}).Decode(memio.NewFile(reply))
+				if len(reply) > maxSplitReplySize {
+					t.Fatalf("Reply is larger than %d: %d", maxSplitReplySize, len(reply))
+				}
+			}
+		}
+	})
+}
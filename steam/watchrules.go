@@ -0,0 +1,443 @@
+package steam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+const (
+	watchRulesKey   = "/servers/watchRules"
+	knownPlayersKey = "/servers/knownPlayers"
+
+	// maxKnownNames is the number of names kept for each known player
+	maxKnownNames = 5
+
+	// knownPlayerRetention is how long a known player is kept after they were last seen
+	knownPlayerRetention = 90 * 24 * time.Hour
+
+	// webhookTimeout is how long to wait for a webhook to respond
+	webhookTimeout = 5 * time.Second
+
+	// maxWebhookRedirects is the number of redirects followed when posting a webhook
+	maxWebhookRedirects = 10
+)
+
+var (
+	ErrNoWatchRule     = errors.New("No such watch rule")
+	ErrEmptyWatchRule  = errors.New("Watch rule has no conditions")
+	ErrWebhookNotLocal = errors.New("Webhook must be a local http URL")
+)
+
+// WatchRule is a set of conditions on a server that trigger an alert when they're all met
+type WatchRule struct {
+	ID string `json:"id"`
+	// Addr is the server the rule applies to, or empty for all sampled servers
+	Addr     string `json:"addr"`
+	Disabled bool   `json:"disabled"`
+
+	// MinPlayers is met when the server has at least MinPlayers players
+	MinPlayers int `json:"minPlayers"`
+	// Map is met when the server is playing the map
+	Map string `json:"map"`
+	// PlayerName is met when a player with the name is on the server
+	PlayerName string `json:"playerName"`
+	// PlayerID is met when a player with any name the SteamID was seen with in status tables is on the server
+	PlayerID ID `json:"playerID"`
+
+	// Notify requests a desktop notification
+	Notify bool `json:"notify"`
+	// Webhook is a local URL the alert is posted to as JSON
+	Webhook string `json:"webhook"`
+}
+
+func (r WatchRule) appliesTo(addr string) bool {
+	return !r.Disabled && (r.Addr == "" || r.Addr == addr)
+}
+
+func (r WatchRule) wantsPlayers() bool {
+	return r.PlayerName != "" || r.PlayerID != 0
+}
+
+// WatchAlert is emitted when a watch rule's conditions become met
+type WatchAlert struct {
+	Rule   WatchRule  `json:"rule"`
+	Server ServerInfo `json:"server"`
+	// Players are the names of the players that met the rule's player condition
+	Players []string  `json:"players"`
+	Message string    `json:"message"`
+	Ts      time.Time `json:"ts"`
+}
+
+// KnownPlayer is a player seen in a status table
+type KnownPlayer struct {
+	ID ID `json:"id"`
+	// Names are the names the player was seen with, most recent first
+	Names    []string  `json:"names"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// checkLocalWebhook returns an error if s isn't an http URL on the local machine
+func checkLocalWebhook(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWebhookNotLocal, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrWebhookNotLocal
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return ErrWebhookNotLocal
+}
+
+// Watcher evaluates watch rules against sampled server info
+//
+// Alerts are edge-triggered: a rule alerts once when its conditions become met on a server,
+// and again only after they stop being met.
+type Watcher struct {
+	DB *store.DB
+	// PlayersMaxAge is how long cached player lists are used for
+	PlayersMaxAge time.Duration
+	// Alert is called with each alert
+	Alert func(WatchAlert)
+	// Client is used to post webhooks, a zero http.Client is used if it's nil
+	//
+	// Redirects are only followed to local URLs.
+	Client *http.Client
+
+	mu sync.Mutex
+	// met is the set of rule and server pairs whose conditions were met on the last check
+	met map[string]bool
+}
+
+// Rules returns the watch rules
+func (w *Watcher) Rules() ([]WatchRule, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	l, err := getOrEmpty[[]WatchRule](w.DB, watchRulesKey)
+	if err != nil {
+		return nil, fmt.Errorf("Watcher.Rules: %w", err)
+	}
+	return l, nil
+}
+
+// updateRules calls f with the rules, and saves the rules it returns if it doesn't return an error
+func (w *Watcher) updateRules(f func(l []WatchRule) ([]WatchRule, error)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	l, err := getOrEmpty[[]WatchRule](w.DB, watchRulesKey)
+	if err != nil {
+		return err
+	}
+	l, err = f(l)
+	if err != nil {
+		return err
+	}
+	return store.Put(w.DB, watchRulesKey, l)
+}
+
+// SetRule adds the rule r, or replaces the rule with the same ID
+//
+// Rules without an ID are given one.
+func (w *Watcher) SetRule(r WatchRule) (WatchRule, error) {
+	r.Addr = strings.TrimSpace(r.Addr)
+	r.Map = strings.TrimSpace(r.Map)
+	r.PlayerName = strings.TrimSpace(r.PlayerName)
+	r.Webhook = strings.TrimSpace(r.Webhook)
+	if r.MinPlayers <= 0 && r.Map == "" && !r.wantsPlayers() {
+		return r, fmt.Errorf("SetRule: %w", ErrEmptyWatchRule)
+	}
+	if r.Webhook != "" {
+		if err := checkLocalWebhook(r.Webhook); err != nil {
+			return r, fmt.Errorf("SetRule: %w", err)
+		}
+	}
+	if r.ID == "" {
+		r.ID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	err := w.updateRules(func(l []WatchRule) ([]WatchRule, error) {
+		for i, p := range l {
+			if p.ID == r.ID {
+				l[i] = r
+				return l, nil
+			}
+		}
+		return append(l, r), nil
+	})
+	if err != nil {
+		return r, fmt.Errorf("SetRule: %w", err)
+	}
+	return r, nil
+}
+
+// RemoveRule removes the rule with ID id
+func (w *Watcher) RemoveRule(id string) error {
+	return w.updateRules(func(l []WatchRule) ([]WatchRule, error) {
+		for i, r := range l {
+			if r.ID == id {
+				return append(l[:i], l[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("RemoveRule(%s): %w", id, ErrNoWatchRule)
+	})
+}
+
+// Addrs returns the servers the enabled rules apply to
+func (w *Watcher) Addrs() []string {
+	rules, _ := w.Rules()
+	var l []string
+	for _, r := range rules {
+		if !r.Disabled && r.Addr != "" {
+			l = append(l, r.Addr)
+		}
+	}
+	return l
+}
+
+// SeePlayers records the SteamIDs and names of players seen in a status table
+//
+// Players that weren't seen for knownPlayerRetention are forgotten.
+func (w *Watcher) SeePlayers(l []Profile, ts time.Time) error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	m, err := getOrEmpty[map[ID]KnownPlayer](w.DB, knownPlayersKey)
+	if err != nil {
+		return fmt.Errorf("SeePlayers: %w", err)
+	}
+	if m == nil {
+		m = map[ID]KnownPlayer{}
+	}
+	for _, p := range l {
+		if p.UserID == 0 || p.Name == "" {
+			continue
+		}
+		kp := m[p.UserID]
+		kp.ID = p.UserID
+		kp.LastSeen = ts.UTC()
+		names := []string{p.Name}
+		for _, s := range kp.Names {
+			if s != p.Name && len(names) < maxKnownNames {
+				names = append(names, s)
+			}
+		}
+		kp.Names = names
+		m[p.UserID] = kp
+	}
+	expire := ts.Add(-knownPlayerRetention)
+	for id, kp := range m {
+		if kp.LastSeen.Before(expire) {
+			delete(m, id)
+		}
+	}
+	if err := store.Put(w.DB, knownPlayersKey, m); err != nil {
+		return fmt.Errorf("SeePlayers: %w", err)
+	}
+	return nil
+}
+
+// KnownPlayer returns the player with SteamID id seen in status tables
+func (w *Watcher) KnownPlayer(id ID) (KnownPlayer, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	m, _ := getOrEmpty[map[ID]KnownPlayer](w.DB, knownPlayersKey)
+	kp, ok := m[id]
+	return kp, ok
+}
+
+// matchPlayers returns the names of the players that meet the rule's player condition
+func (w *Watcher) matchPlayers(r WatchRule, players []ServerPlayer) []string {
+	names := map[string]bool{}
+	if r.PlayerName != "" {
+		names[strings.ToLower(r.PlayerName)] = true
+	}
+	if r.PlayerID != 0 {
+		kp, _ := w.KnownPlayer(r.PlayerID)
+		for _, s := range kp.Names {
+			names[strings.ToLower(s)] = true
+		}
+	}
+	var l []string
+	for _, p := range players {
+		if names[strings.ToLower(strings.TrimSpace(p.Name))] {
+			l = append(l, p.Name)
+		}
+	}
+	sort.Strings(l)
+	return l
+}
+
+// Check evaluates the rules that apply to inf's server, and alerts those whose conditions became met
+func (w *Watcher) Check(ctx context.Context, inf ServerInfo) error {
+	rules, err := w.Rules()
+	if err != nil {
+		return fmt.Errorf("Watcher.Check: %w", err)
+	}
+
+	var players []ServerPlayer
+	var playersErr error
+	playersDone := false
+	var errs []error
+	for _, r := range rules {
+		if !r.appliesTo(inf.Addr) {
+			continue
+		}
+
+		met := (r.MinPlayers <= 0 || inf.Players >= r.MinPlayers) &&
+			(r.Map == "" || strings.EqualFold(inf.Map, r.Map))
+		var matched []string
+		if met && r.wantsPlayers() {
+			if !playersDone {
+				playersDone = true
+				players, playersErr = QueryPlayers(w.DB, w.PlayersMaxAge, inf.Addr)
+			}
+			if playersErr != nil {
+				// keep the rule's state until the players are known
+				errs = append(errs, playersErr)
+				continue
+			}
+			matched = w.matchPlayers(r, players)
+			met = len(matched) != 0
+		}
+
+		if !w.setMet(r.ID+"\x00"+inf.Addr, met) {
+			continue
+		}
+		alert := WatchAlert{
+			Rule:    r,
+			Server:  inf,
+			Players: matched,
+			Message: alertMessage(r, inf, matched),
+			Ts:      time.Now(),
+		}
+		if w.Alert != nil {
+			w.Alert(alert)
+		}
+		if r.Webhook != "" {
+			if err := w.postWebhook(ctx, r.Webhook, alert); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("Watcher.Check: %w", err)
+	}
+	return nil
+}
+
+// setMet records whether the rule and server pair k is met, and reports whether it just became met
+func (w *Watcher) setMet(k string, met bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.met == nil {
+		w.met = map[string]bool{}
+	}
+	was := w.met[k]
+	if met {
+		w.met[k] = true
+	} else {
+		delete(w.met, k)
+	}
+	return met && !was
+}
+
+// prune forgets whether rules were met on servers that are no longer sampled
+func (w *Watcher) prune(servers map[string]Region) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for k := range w.met {
+		_, addr, _ := strings.Cut(k, "\x00")
+		if _, ok := servers[addr]; !ok {
+			delete(w.met, k)
+		}
+	}
+}
+
+func alertMessage(r WatchRule, inf ServerInfo, players []string) string {
+	name := inf.Name
+	if name == "" {
+		name = inf.Addr
+	}
+	var l []string
+	if r.MinPlayers > 0 {
+		l = append(l, fmt.Sprintf("has %d/%d players", inf.Players, inf.MaxPlayers))
+	}
+	if r.Map != "" {
+		l = append(l, fmt.Sprintf("is playing %s", inf.Map))
+	}
+	if len(players) != 0 {
+		l = append(l, fmt.Sprintf("has %s on it", strings.Join(players, ", ")))
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(l, " and "))
+}
+
+func (w *Watcher) postWebhook(ctx context.Context, u string, alert WatchAlert) error {
+	if err := checkLocalWebhook(u); err != nil {
+		return fmt.Errorf("postWebhook: %w", err)
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("postWebhook: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("postWebhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := http.Client{}
+	if w.Client != nil {
+		client = *w.Client
+	}
+	// a local webhook must not be able to redirect the alert to another host
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := checkLocalWebhook(req.URL.String()); err != nil {
+			return err
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= maxWebhookRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxWebhookRedirects)
+		}
+		return nil
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("postWebhook: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("postWebhook: %s: %s", u, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package steam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+)
+
+func TestWatcher(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	addr := serveUDP(t, func(req []byte) [][]byte { return [][]byte{playersPacket} })
+	hooks := make(chan WatchAlert, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a WatchAlert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Error(err)
+		}
+		hooks <- a
+	}))
+	defer hook.Close()
+
+	var alerts []WatchAlert
+	w := &Watcher{DB: db, PlayersMaxAge: -1, Alert: func(a WatchAlert) { alerts = append(alerts, a) }}
+
+	if _, err := w.SetRule(WatchRule{Addr: addr}); !errors.Is(err, ErrEmptyWatchRule) {
+		t.Fatalf("Expected ErrEmptyWatchRule; Got %v", err)
+	}
+	if _, err := w.SetRule(WatchRule{MinPlayers: 1, Webhook: "http://example.com/hook"}); !errors.Is(err, ErrWebhookNotLocal) {
+		t.Fatalf("Expected ErrWebhookNotLocal; Got %v", err)
+	}
+	full, err := w.SetRule(WatchRule{Addr: addr, MinPlayers: 20, Map: "CTF_2fort", Webhook: hook.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	friend, err := w.SetRule(WatchRule{Addr: addr, PlayerID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SeePlayers([]Profile{{UserID: 42, Name: "Kyle"}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	inf := ServerInfo{Addr: addr, Name: "Uncletopia", Players: 20, MaxPlayers: 24, Map: "ctf_2fort"}
+	check := func(players int) []WatchAlert {
+		alerts = nil
+		inf.Players = players
+		if err := w.Check(context.Background(), inf); err != nil {
+			t.Fatal(err)
+		}
+		return alerts
+	}
+
+	l := check(20)
+	if len(l) != 2 || l[0].Rule.ID != full.ID || l[1].Rule.ID != friend.ID {
+		t.Fatalf("Expected both rules to alert; Got %+v", l)
+	}
+	if l[1].Players[0] != "Kyle" || l[0].Message != "Uncletopia has 20/24 players and is playing ctf_2fort" {
+		t.Fatalf("Unexpected alert details; Got %+v", l)
+	}
+	select {
+	case a := <-hooks:
+		if a.Rule.ID != full.ID || a.Server.Players != 20 {
+			t.Fatalf("Expected the webhook to receive the alert; Got %+v", a)
+		}
+	default:
+		t.Fatal("Expected the webhook to be called")
+	}
+
+	// rules alert again only after they stop being met
+	if l := check(21); len(l) != 0 {
+		t.Fatalf("Expected no alerts while the rules stay met; Got %+v", l)
+	}
+	if l := check(5); len(l) != 0 {
+		t.Fatalf("Expected no alerts; Got %+v", l)
+	}
+	if l := check(22); len(l) != 1 || l[0].Rule.ID != full.ID {
+		t.Fatalf("Expected the player count rule to alert again; Got %+v", l)
+	}
+
+	// rules alert again after their server stops being sampled
+	w.prune(map[string]Region{})
+	if l := check(22); len(l) != 2 {
+		t.Fatalf("Expected both rules to alert after the server was pruned; Got %+v", l)
+	}
+
+	// players that weren't seen for a long time are forgotten
+	if err := w.SeePlayers([]Profile{{UserID: 7, Name: "Stan"}}, time.Now().Add(-knownPlayerRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.KnownPlayer(7); !ok {
+		t.Fatal("Expected the player to be known")
+	}
+	if err := w.SeePlayers([]Profile{{UserID: 42, Name: "Kyle"}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.KnownPlayer(7); ok {
+		t.Fatal("Expected the player not seen since knownPlayerRetention to be forgotten")
+	}
+	if _, ok := w.KnownPlayer(42); !ok {
+		t.Fatal("Expected the recently seen player to be kept")
+	}
+
+	if err := w.RemoveRule(friend.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RemoveRule(friend.ID); !errors.Is(err, ErrNoWatchRule) {
+		t.Fatalf("Expected ErrNoWatchRule; Got %v", err)
+	}
+	if l := w.Addrs(); len(l) != 1 || l[0] != addr {
+		t.Fatalf("Expected the rule's server to be sampled; Got %v", l)
+	}
+}
+
+func TestWebhookRedirect(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/local":
+			http.Redirect(w, r, "/hook", http.StatusTemporaryRedirect)
+		case "/remote":
+			http.Redirect(w, r, "http://example.com/hook", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer hook.Close()
+
+	w := &Watcher{Client: hook.Client()}
+	if err := w.postWebhook(context.Background(), hook.URL+"/local", WatchAlert{}); err != nil {
+		t.Fatalf("Expected local redirects to be followed; Got %v", err)
+	}
+	if err := w.postWebhook(context.Background(), hook.URL+"/remote", WatchAlert{}); !errors.Is(err, ErrWebhookNotLocal) {
+		t.Fatalf("Expected ErrWebhookNotLocal; Got %v", err)
+	}
+}
@@ -13,6 +13,8 @@ import (
 var (
 	ErrNilDB = errors.New("Use of uninitialized DB")
 	ErrStale = errors.New("Stale")
+	// ErrNotFound is returned by Get if the key doesn't exist
+	ErrNotFound = pebble.ErrNotFound
 )
 
 type DB struct {
@@ -0,0 +1,297 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/logs"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	Logs = logs.AppLogger()
+
+	ErrFiltered = errors.New("Filtered")
+
+	// confusables maps look-alike letters from other scripts to their latin equivalent
+	// NFKD handles most of the rest (full-width, mathematical, circled, etc.)
+	confusables = map[rune]rune{
+		'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+		'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's',
+		'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+		'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+		'ß': 's', 'ø': 'o', 'đ': 'd', 'ł': 'l', 'ı': 'i',
+	}
+
+	leetspeak = map[rune]rune{
+		'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+		'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't',
+	}
+)
+
+// FoldConfusables lower-cases s, and folds look-alike and decorated characters to plain latin letters
+func FoldConfusables(s string) string {
+	s = norm.NFKD.String(s)
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		r = unicode.ToLower(r)
+		if c, ok := confusables[r]; ok {
+			return c
+		}
+		return r
+	}, s)
+}
+
+// NormalizeLeet folds confusables and converts leetspeak in word to letters
+//
+// Words that contain no letters (e.g. numbers) are left as-is.
+func NormalizeLeet(word string) string {
+	word = FoldConfusables(word)
+	if !strings.ContainsFunc(word, unicode.IsLetter) {
+		return word
+	}
+	// trailing punctuation is more likely to be punctuation than leetspeak
+	word = strings.TrimRight(word, `!?.,;:'")`)
+	word = strings.Map(func(r rune) rune {
+		if c, ok := leetspeak[r]; ok {
+			return c
+		}
+		return r
+	}, word)
+	return strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+type filterRule struct {
+	config.FilterRule
+	words []string
+	re    *regexp.Regexp
+}
+
+// Filter is a compiled config.FilterPolicy
+type Filter struct {
+	Action string
+	rules  []filterRule
+}
+
+// FilterMatch describes a rule that matched a message
+type FilterMatch struct {
+	Rule   config.FilterRule
+	Action string
+	Text   string
+}
+
+// FilterSegment is part of a filtered message
+// Either Text is set, or Bleep is true.
+type FilterSegment struct {
+	Text  string
+	Bleep bool
+}
+
+type FilterResult struct {
+	Dropped  bool
+	Matches  []FilterMatch
+	Segments []FilterSegment
+}
+
+// Text returns the filtered message, without any bleeps
+func (fr FilterResult) Text() string {
+	l := make([]string, 0, len(fr.Segments))
+	for _, seg := range fr.Segments {
+		if seg.Text != "" {
+			l = append(l, seg.Text)
+		}
+	}
+	return strings.Join(l, " ")
+}
+
+// HasBleep reports whether any of the segments is a bleep
+func (fr FilterResult) HasBleep() bool {
+	for _, seg := range fr.Segments {
+		if seg.Bleep {
+			return true
+		}
+	}
+	return false
+}
+
+// NewFilter compiles the policy p
+//
+// If p is disabled, a nil *Filter is returned, which matches nothing.
+// Rules with invalid patterns are skipped and reported in the error,
+// but the filter of the remaining rules is still returned.
+func NewFilter(p config.FilterPolicy) (*Filter, error) {
+	if !p.IsEnabled() || len(p.Rules) == 0 {
+		return nil, nil
+	}
+	f := &Filter{Action: p.Action}
+	var errs []error
+	for _, r := range p.Rules {
+		fr := filterRule{FilterRule: r}
+		if fr.Action == "" {
+			fr.Action = p.Action
+		}
+		switch fr.Match {
+		case config.FilterRegex:
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("`%s`: %w", r.Pattern, err))
+				continue
+			}
+			fr.re = re
+		case config.FilterSubstring:
+			fr.words = []string{NormalizeLeet(r.Pattern)}
+		default:
+			for _, w := range strings.Fields(r.Pattern) {
+				fr.words = append(fr.words, NormalizeLeet(w))
+			}
+		}
+		if fr.re == nil && (len(fr.words) == 0 || fr.words[0] == "") {
+			continue
+		}
+		f.rules = append(f.rules, fr)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return f, fmt.Errorf("NewFilter: %w", err)
+	}
+	return f, nil
+}
+
+// CheckFilterConfig returns an error describing the invalid rules in each of c's policies
+func CheckFilterConfig(c config.FilterConfig) error {
+	var errs []error
+	check := func(name string, p config.FilterPolicy) {
+		for _, r := range p.Rules {
+			if r.Match != config.FilterRegex {
+				continue
+			}
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				errs = append(errs, fmt.Errorf("%s rule `%s`: %w", name, r.Pattern, err))
+			}
+		}
+	}
+	check("filter", c.FilterPolicy)
+	for k, p := range c.Games {
+		check("filter.games."+k, p)
+	}
+	for k, p := range c.Servers {
+		check("filter.servers."+k, p)
+	}
+	return errors.Join(errs...)
+}
+
+func (fr filterRule) match(words []string, joined string, offsets []int, hit func(i, j int)) {
+	switch {
+	case fr.re != nil:
+		for _, m := range fr.re.FindAllStringIndex(joined, -1) {
+			i, j := -1, -1
+			for k, off := range offsets {
+				end := off + len(words[k])
+				if end > m[0] && off < m[1] {
+					if i < 0 {
+						i = k
+					}
+					j = k + 1
+				}
+			}
+			if i >= 0 {
+				hit(i, j)
+			}
+		}
+	case fr.Match == config.FilterSubstring:
+		for i, w := range words {
+			if strings.Contains(w, fr.words[0]) {
+				hit(i, i+1)
+			}
+		}
+	default:
+		n := len(fr.words)
+	outer:
+		for i := 0; i+n <= len(words); i++ {
+			for k, w := range fr.words {
+				if words[i+k] != w {
+					continue outer
+				}
+			}
+			hit(i, i+n)
+		}
+	}
+}
+
+// Apply filters text
+func (f *Filter) Apply(text string) FilterResult {
+	words := strings.Fields(text)
+	if f == nil || len(words) == 0 {
+		return FilterResult{Segments: []FilterSegment{{Text: strings.Join(words, " ")}}}
+	}
+
+	normWords := make([]string, len(words))
+	offsets := make([]int, len(words))
+	joined := strings.Builder{}
+	for i, w := range words {
+		if i > 0 {
+			joined.WriteByte(' ')
+		}
+		normWords[i] = NormalizeLeet(w)
+		offsets[i] = joined.Len()
+		joined.WriteString(normWords[i])
+	}
+
+	res := FilterResult{}
+	actions := make([]string, len(words))
+	for _, rule := range f.rules {
+		rule.match(normWords, joined.String(), offsets, func(i, j int) {
+			res.Matches = append(res.Matches, FilterMatch{
+				Rule:   rule.FilterRule,
+				Action: rule.Action,
+				Text:   strings.Join(words[i:j], " "),
+			})
+			for k := i; k < j; k++ {
+				// bleep takes precedence over mask, so we don't hide that something was said
+				if actions[k] != config.FilterBleep {
+					actions[k] = rule.Action
+				}
+			}
+			if rule.Action == config.FilterDrop {
+				res.Dropped = true
+			}
+		})
+	}
+
+	for i, w := range words {
+		switch actions[i] {
+		case config.FilterMask, config.FilterDrop:
+		case config.FilterBleep:
+			if n := len(res.Segments); n == 0 || !res.Segments[n-1].Bleep {
+				res.Segments = append(res.Segments, FilterSegment{Bleep: true})
+			}
+		default:
+			if n := len(res.Segments); n != 0 && !res.Segments[n-1].Bleep {
+				res.Segments[n-1].Text += " " + w
+			} else {
+				res.Segments = append(res.Segments, FilterSegment{Text: w})
+			}
+		}
+	}
+	return res
+}
+
+// Log logs the matches in fr
+func (fr FilterResult) Log(username, text string) {
+	for _, m := range fr.Matches {
+		Logs.Info("filtered",
+			slog.String("username", username),
+			slog.String("message", text),
+			slog.String("match", m.Text),
+			slog.String("pattern", m.Rule.Pattern),
+			slog.String("mode", m.Rule.Match),
+			slog.String("action", m.Action),
+		)
+	}
+}
@@ -0,0 +1,116 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+func TestNormalizeLeet(t *testing.T) {
+	cases := map[string]string{
+		"sh1t":   "shit",
+		"$h!t!!": "shit",
+		"ＳＨＩＴ":   "shit",
+		"ѕhіt":   "shit",
+		"shít":   "shit",
+		"1337":   "1337",
+		"n00b,":  "noob",
+	}
+	for in, want := range cases {
+		if got := NormalizeLeet(in); got != want {
+			t.Fatalf("NormalizeLeet(`%s`): Expected `%s`; Got `%s`", in, want, got)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f, err := NewFilter(config.FilterPolicy{
+		Action: config.FilterMask,
+		Rules: []config.FilterRule{
+			{Pattern: "shit"},
+			{Pattern: "bad word", Action: config.FilterBleep},
+			{Pattern: "heck", Match: config.FilterSubstring, Action: config.FilterBleep},
+			{Pattern: `^kill yourself`, Match: config.FilterRegex, Action: config.FilterDrop},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Text    string
+		Out     string
+		Bleep   bool
+		Dropped bool
+	}{
+		{"well sh1t happens", "well happens", false, false},
+		{"not shitty", "not shitty", false, false},
+		{"a bad word here", "a here", true, false},
+		{"what the heckin", "what the", true, false},
+		{"kill yourself now", "now", false, true},
+		{"gg", "gg", false, false},
+	}
+	for _, c := range cases {
+		res := f.Apply(c.Text)
+		switch {
+		case res.Text() != c.Out:
+			t.Fatalf("Apply(`%s`): Expected text `%s`; Got `%s`", c.Text, c.Out, res.Text())
+		case res.HasBleep() != c.Bleep:
+			t.Fatalf("Apply(`%s`): Expected bleep %v; Got %v", c.Text, c.Bleep, res.HasBleep())
+		case res.Dropped != c.Dropped:
+			t.Fatalf("Apply(`%s`): Expected dropped %v; Got %v", c.Text, c.Dropped, res.Dropped)
+		}
+	}
+}
+
+func TestFilterPolicy(t *testing.T) {
+	off := false
+	cfg := config.FilterConfig{
+		FilterPolicy: config.FilterPolicy{
+			Rules: []config.FilterRule{{Pattern: "shit"}},
+		},
+		Games: map[string]config.FilterPolicy{
+			"1012110": {Rules: []config.FilterRule{{Pattern: "heck"}}},
+		},
+		Servers: map[string]config.FilterPolicy{
+			"127.0.0.1:27015": {Enabled: &off},
+		},
+	}
+
+	f, _ := NewFilter(cfg.Policy("1012110", "1.2.3.4:27015"))
+	if s := f.Apply("shit heck ok").Text(); s != "ok" {
+		t.Fatalf("Expected game rules to be added; Got `%s`", s)
+	}
+
+	f, _ = NewFilter(cfg.Policy("1012110", "127.0.0.1:27015"))
+	if s := f.Apply("shit heck ok").Text(); s != "shit heck ok" {
+		t.Fatalf("Expected server policy to disable filtering; Got `%s`", s)
+	}
+}
+
+func TestFilterInvalidRule(t *testing.T) {
+	cfg := config.FilterConfig{
+		FilterPolicy: config.FilterPolicy{
+			Action: config.FilterDrop,
+			Rules: []config.FilterRule{
+				{Pattern: "heck"},
+				{Pattern: "(unclosed", Match: config.FilterRegex},
+			},
+		},
+		Games: map[string]config.FilterPolicy{
+			"440": {Rules: []config.FilterRule{{Pattern: "[z-a]", Match: config.FilterRegex}}},
+		},
+	}
+	if err := CheckFilterConfig(cfg); err == nil || !strings.Contains(err.Error(), "(unclosed") || !strings.Contains(err.Error(), "filter.games.440") {
+		t.Fatalf("Expected both invalid rules to be reported; Got %v", err)
+	}
+
+	f, err := NewFilter(cfg.Policy("", ""))
+	if err == nil {
+		t.Fatal("Expected the invalid rule to be reported")
+	}
+	if s := f.Apply("heck ok").Text(); s != "ok" {
+		t.Fatalf("Expected the valid rules to still apply; Got `%s`", s)
+	}
+}
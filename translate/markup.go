@@ -0,0 +1,114 @@
+package translate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// markupPat matches *emphasis*, ~slow~, [voice=name] and {sound}
+	markupPat = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*|~(\S(?:[^~]*?\S)?)~|\[voice=([^\]\s]*)\]|\{([^{}\s]+)\}`)
+)
+
+// Style is the speech style of a segment, set by chat markup
+type Style struct {
+	// Emphasis is set by *text*
+	Emphasis bool `json:"emphasis"`
+	// Slow is set by ~text~, it's spoken slower and quieter
+	Slow bool `json:"slow"`
+	// Voice is set by [voice=name], and applies until the next voice switch; [voice=] switches back
+	Voice string `json:"voice"`
+}
+
+// markupWordRune reports whether r is part of a word, so markers next to it aren't markup e.g. 2*3*4
+func markupWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ParseMarkup splits text into segments, with the style set by its markup
+//
+// Markup is removed from the text. Markers that aren't at a word boundary are left as-is.
+// {name} is a Sound segment if isSound(name) is true, otherwise it's text.
+func ParseMarkup(text string, isSound func(name string) bool) []Segment {
+	var segs []Segment
+	style := Style{}
+	add := func(seg Segment) {
+		seg.Text = strings.TrimSpace(seg.Text)
+		if seg.Text == "" {
+			return
+		}
+		// merge adjacent plain text e.g. from markers that weren't markup
+		if n := len(segs); n > 0 && !seg.Sound && !segs[n-1].Sound && segs[n-1].Style == seg.Style {
+			segs[n-1].Text += " " + seg.Text
+			return
+		}
+		segs = append(segs, seg)
+	}
+
+	pos := 0
+	for _, m := range markupPat.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if start < pos {
+			continue
+		}
+		if text[start] == '*' || text[start] == '~' {
+			prev, _ := utf8.DecodeLastRuneInString(text[:start])
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if markupWordRune(prev) || markupWordRune(next) {
+				continue
+			}
+		}
+
+		add(Segment{Text: text[pos:start], Style: style})
+		pos = end
+		switch {
+		case m[2] >= 0:
+			st := style
+			st.Emphasis = true
+			add(Segment{Text: text[m[2]:m[3]], Style: st})
+		case m[4] >= 0:
+			st := style
+			st.Slow = true
+			add(Segment{Text: text[m[4]:m[5]], Style: st})
+		case m[6] >= 0:
+			style.Voice = text[m[6]:m[7]]
+		case m[8] >= 0:
+			name := text[m[8]:m[9]]
+			add(Segment{Text: name, Sound: isSound != nil && isSound(name), Style: style})
+		}
+	}
+	add(Segment{Text: text[pos:], Style: style})
+	return segs
+}
+
+// markupStage parses chat markup, see ParseMarkup
+type markupStage struct{}
+
+func (markupStage) Name() string { return "markup" }
+
+func (markupStage) Transform(env Env, msg Message) Message {
+	var segs []Segment
+	for _, seg := range msg.Segments {
+		if seg.Bleep || seg.Sound {
+			segs = append(segs, seg)
+			continue
+		}
+		for _, s := range ParseMarkup(seg.Text, env.IsSound) {
+			s.Style = mergeStyle(seg.Style, s.Style)
+			segs = append(segs, s)
+		}
+	}
+	msg.Segments = segs
+	return msg
+}
+
+func mergeStyle(a, b Style) Style {
+	a.Emphasis = a.Emphasis || b.Emphasis
+	a.Slow = a.Slow || b.Slow
+	if b.Voice != "" {
+		a.Voice = b.Voice
+	}
+	return a
+}
@@ -0,0 +1,71 @@
+package translate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	isSound := func(name string) bool { return name == "wilhelm" }
+	em := Style{Emphasis: true}
+	slow := Style{Slow: true}
+	alan := Style{Voice: "alan"}
+
+	cases := []struct {
+		Text string
+		Exp  []Segment
+	}{
+		{"", nil},
+		{"no markup here", []Segment{{Text: "no markup here"}}},
+		{"that was *really* good", []Segment{{Text: "that was"}, {Text: "really", Style: em}, {Text: "good"}}},
+		{"*so very* nice", []Segment{{Text: "so very", Style: em}, {Text: "nice"}}},
+		{"~shh be quiet~", []Segment{{Text: "shh be quiet", Style: slow}}},
+		{"hi [voice=alan] hello *there*", []Segment{{Text: "hi"}, {Text: "hello", Style: alan}, {Text: "there", Style: Style{Voice: "alan", Emphasis: true}}}},
+		{"[voice=alan] one [voice=] two", []Segment{{Text: "one", Style: alan}, {Text: "two"}}},
+		{"ouch {wilhelm}", []Segment{{Text: "ouch"}, {Text: "wilhelm", Sound: true}}},
+		{"{notasound} ok", []Segment{{Text: "notasound ok"}}},
+
+		// markers that aren't at word boundaries, or aren't closed, are left as-is
+		{"2*3*4", []Segment{{Text: "2*3*4"}}},
+		{"5 * 3 = 15", []Segment{{Text: "5 * 3 = 15"}}},
+		{"a ~ b", []Segment{{Text: "a ~ b"}}},
+		{"* not closed", []Segment{{Text: "* not closed"}}},
+		{"* spaced *", []Segment{{Text: "* spaced *"}}},
+		{"[voice=alan", []Segment{{Text: "[voice=alan"}}},
+		{"*wow*!", []Segment{{Text: "wow", Style: em}, {Text: "!"}}},
+	}
+	for _, c := range cases {
+		segs := ParseMarkup(c.Text, isSound)
+		if !reflect.DeepEqual(segs, c.Exp) {
+			t.Fatalf("ParseMarkup(`%s`): Expected %+v; Got %+v", c.Text, c.Exp, segs)
+		}
+	}
+}
+
+func TestMarkupPipeline(t *testing.T) {
+	defer SetRules(DefaultRules)
+	SetRules(Rules{Translations: map[string][]string{"u": {"you"}, "r": {"are"}, "gr8": {"great"}}})
+
+	p, err := NewPipeline([]string{"markup", "translate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		Text string
+		Out  string
+	}{
+		{"*wow* [voice=alan] ~nice~", "*wow* [voice=alan] ~nice~"},
+		{"u r *gr8*", "you are *great*"},
+	}
+	for _, c := range cases {
+		if s := p.Run(Env{}, c.Text).Output.String(); s != c.Out {
+			t.Fatalf("Run(`%s`): Expected `%s`; Got `%s`", c.Text, c.Out, s)
+		}
+	}
+
+	// without the markup stage, nothing is parsed
+	p, _ = NewPipeline([]string{"translate"})
+	if s := p.Run(Env{}, "*wow*").Output.String(); s != "*wow*" {
+		t.Fatalf("Expected markup to be left as-is; Got `%s`", s)
+	}
+}
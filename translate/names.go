@@ -0,0 +1,123 @@
+package translate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxNameLen is the maximum length of a speakable name
+	MaxNameLen = 32
+)
+
+var (
+	// xX_name_Xx
+	nameWrapPat = regexp.MustCompile(`^[xX]{2,}[_.\-\s]*(.+?)[_.\-\s]*[xX]{2,}$`)
+
+	nameSeps = func(r rune) bool {
+		return r == '_' || r == '.' || r == '-' || unicode.IsSpace(r)
+	}
+
+	nameLetters = map[rune]rune{'Đ': 'D', 'đ': 'd', 'Ð': 'D', 'ð': 'd', 'Ø': 'O', 'ø': 'o', 'Ł': 'L', 'ł': 'l', 'ß': 's'}
+)
+
+// foldName strips diacritics from s, keeping its case
+func foldName(s string) string {
+	s = norm.NFKD.String(s)
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		if c, ok := nameLetters[r]; ok {
+			return c
+		}
+		return r
+	}, s)
+}
+
+// deleetName converts the leetspeak in word, unless it's only a numeric suffix e.g. Player123
+func deleetName(word string) string {
+	head := strings.TrimRightFunc(word, unicode.IsDigit)
+	if !strings.ContainsFunc(head, unicode.IsLetter) {
+		return word
+	}
+	// ! and | are more likely to be decoration in names
+	return strings.Map(func(r rune) rune {
+		if c, ok := leetspeak[r]; ok && r != '!' && r != '|' {
+			return c
+		}
+		return r
+	}, head) + word[len(head):]
+}
+
+// splitName splits word on camelCase and letter-digit boundaries e.g. FPSDoug22 is FPS Doug 22
+func splitName(word string) []string {
+	rs := []rune(word)
+	var l []string
+	start := 0
+	for i := 1; i < len(rs); i++ {
+		p, r := rs[i-1], rs[i]
+		split := false
+		switch {
+		case unicode.IsLower(p) && unicode.IsUpper(r):
+			split = true
+		case unicode.IsUpper(p) && unicode.IsUpper(r) && i+1 < len(rs) && unicode.IsLower(rs[i+1]):
+			split = true
+		case unicode.IsDigit(p) != unicode.IsDigit(r):
+			split = true
+		}
+		if split {
+			l = append(l, string(rs[start:i]))
+			start = i
+		}
+	}
+	return append(l, string(rs[start:]))
+}
+
+// SpeakableName derives a name that TTS can say from the username
+//
+// The clan tag and decorative characters are removed, diacritics and leetspeak are folded,
+// and camelCase, underscores, etc. are split into words.
+// If nothing speakable is left, an empty string is returned.
+func SpeakableName(username string) string {
+	_, name := ClanName(username)
+	name = foldName(name)
+	if m := nameWrapPat.FindStringSubmatch(name); m != nil {
+		name = m[1]
+	}
+
+	var words []string
+	for _, w := range strings.FieldsFunc(name, nameSeps) {
+		// decorations inside a word separate its parts e.g. FPS!DOUG
+		w = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' {
+				return r
+			}
+			return ' '
+		}, deleetName(w))
+		for _, w := range strings.Fields(w) {
+			if w = strings.Trim(w, "'"); w != "" {
+				words = append(words, splitName(w)...)
+			}
+		}
+	}
+
+	name = ""
+	for _, w := range words {
+		switch {
+		case name == "" && len(w) > MaxNameLen:
+			rs := []rune(w)
+			return string(rs[:min(len(rs), MaxNameLen/2)])
+		case len(name)+1+len(w) > MaxNameLen:
+			return name
+		case name == "":
+			name = w
+		default:
+			name += " " + w
+		}
+	}
+	return name
+}
@@ -0,0 +1,29 @@
+package translate
+
+import (
+	"testing"
+)
+
+func TestSpeakableName(t *testing.T) {
+	cases := map[string]string{
+		"* PP * FPS!DOUG ★": "FPS DOUG",
+		"(PP) ★TAGI★":       "TAGI",
+		"xX_Sn1p3r_Xx":      "Sniper",
+		"DarkKnight":        "Dark Knight",
+		"FPSDoug22":         "FPS Doug 22",
+		"Player123":         "Player 123",
+		"n00b_slayer":       "noob slayer",
+		"Nguyễn Ngọc Ẩn":    "Nguyen Ngoc An",
+		"Đỗ Anh Khôi":       "Do Anh Khoi",
+		"Carter O'Reilley":  "Carter O'Reilley",
+		"Vincent K. Brooks": "Vincent K Brooks",
+		"ＫＹＬＥ":              "KYLE",
+		"★★★":               "",
+		"|PP| 1337":         "1337",
+	}
+	for in, want := range cases {
+		if got := SpeakableName(in); got != want {
+			t.Fatalf("SpeakableName(`%s`): Expected `%s`; Got `%s`", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package translate
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+const (
+	LinkText = "a link"
+)
+
+var (
+	linkPats = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://\S+`),
+		regexp.MustCompile(`(?i)\bwww\.\S+`),
+		regexp.MustCompile(`(?i)\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|net|org|io|gg|tv|me|co|uk|de|ru|fr|info|xyz|ly|app|dev)\b(?:/\S*)?`),
+		regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d{1,5})?\b`),
+	}
+	linkRunPat = regexp.MustCompile(`\b(?:` + LinkText + `\s+)+` + LinkText + `\b`)
+
+	bangPat   = regexp.MustCompile(`[!?]+(?:1+[!?]*)*`)
+	dotsPat   = regexp.MustCompile(`\.{2,}`)
+	commasPat = regexp.MustCompile(`,{2,}`)
+
+	timePat     = regexp.MustCompile(`(?i)^(\d{1,2})(?::(\d{2}))?([ap])\.?m\.?$`)
+	clockPat    = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	hourPat     = regexp.MustCompile(`^(?:[1-9]|1[0-2])$`)
+	ordinalPat  = regexp.MustCompile(`(?i)^(\d+)(st|nd|rd|th)$`)
+	numberPat   = regexp.MustCompile(`^(-?)(\d{1,3}(?:,\d{3})+|\d+)(?:\.(\d+))?(%?)$`)
+	meridiemPat = regexp.MustCompile(`(?i)^([ap])\.?m\.?$`)
+
+	emojiWords = map[rune]string{
+		'😂': "haha", '🤣': "haha", '😆': "haha", '😹': "haha",
+		'😭': "crying", '😢': "sad", '🙁': "sad", '☹': "sad", '😞': "sad",
+		'😡': "angry", '😠': "angry", '🤬': "angry",
+		'❤': "love", '😍': "love", '🥰': "love",
+		'💀': "dead", '☠': "dead",
+		'👍': "thumbs up", '👎': "thumbs down",
+		'🔥': "fire", '🤔': "hmm", '😎': "cool", '🙏': "please",
+		'💯': "one hundred", '🎉': "congrats", '😱': "oh my god",
+		'😴': "sleepy", '🤡': "clown", '🐐': "goat", '🏆': "trophy",
+		'🤮': "gross", '😉': "wink", '🙄': "eye roll", '🤷': "shrug",
+		'👀': "eyes", '👋': "hey", '🫡': "salute",
+	}
+
+	smallNumbers = []string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+		"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen",
+	}
+	tensNumbers  = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	scaleNumbers = []struct {
+		N    int64
+		Name string
+	}{
+		{1e9, "billion"},
+		{1e6, "million"},
+		{1e3, "thousand"},
+	}
+	ordinalWords = map[string]string{
+		"one": "first", "two": "second", "three": "third", "five": "fifth",
+		"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+	}
+)
+
+// Normalize applies both NormalizeText and NormalizeSpeech to text
+func Normalize(cfg config.NormalizeConfig, text string) string {
+	return NormalizeSpeech(cfg, NormalizeText(cfg, text))
+}
+
+// NormalizeText cleans up a chat message before it's translated
+//
+// Links, emoji, shouting and repeated characters are handled here
+// so the result can still match translations and substitutes.
+func NormalizeText(cfg config.NormalizeConfig, text string) string {
+	if config.Enabled(cfg.Links) {
+		text = normalizeLinks(text)
+	}
+	if config.Enabled(cfg.Emoji) {
+		text = normalizeEmoji(text)
+	}
+	if config.Enabled(cfg.Caps) {
+		text = normalizeCaps(text)
+	}
+	if config.Enabled(cfg.Repeats) {
+		text = normalizeRepeats(text)
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// NormalizeSpeech prepares translated text to be spoken
+//
+// Numbers are expanded here, after translation, so they don't break substitutes and rewrites that match digits.
+func NormalizeSpeech(cfg config.NormalizeConfig, text string) string {
+	if config.Enabled(cfg.Numbers) {
+		text = normalizeNumbers(text)
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func normalizeLinks(text string) string {
+	for _, re := range linkPats {
+		text = re.ReplaceAllString(text, LinkText)
+	}
+	return linkRunPat.ReplaceAllString(text, LinkText)
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r == 0x200D, r == 0x20E3:
+		// zero-width joiner and keycap
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F:
+		// variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		// skin tones
+		return true
+	}
+	return unicode.Is(unicode.So, r)
+}
+
+func normalizeEmoji(text string) string {
+	s := strings.Builder{}
+	last := ""
+	for _, r := range text {
+		if w, ok := emojiWords[r]; ok {
+			// 😂😂😂 is just one laugh
+			if w != last {
+				s.WriteString(" " + w + " ")
+			}
+			last = w
+			continue
+		}
+		if isEmoji(r) {
+			continue
+		}
+		if !unicode.IsSpace(r) {
+			last = ""
+		}
+		s.WriteRune(r)
+	}
+	return s.String()
+}
+
+// normalizeCaps lower-cases shouting
+//
+// If the whole message is in caps, it's all lower-cased.
+// Otherwise, words of up to 3 letters are assumed to be acronyms, and are kept.
+func normalizeCaps(text string) string {
+	words := strings.Fields(text)
+	upper := 0
+	cased := 0
+	for _, w := range words {
+		switch {
+		case isUpper(w):
+			upper++
+			cased++
+		case strings.IndexFunc(w, unicode.IsLower) >= 0:
+			cased++
+		}
+	}
+	shouting := upper > 1 && upper == cased
+	for i, w := range words {
+		if !isUpper(w) {
+			continue
+		}
+		n := 0
+		for _, r := range w {
+			if unicode.IsLetter(r) {
+				n++
+			}
+		}
+		if shouting || n > 3 {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// isUpper reports whether w contains at least two letters, all of which are upper-case
+func isUpper(w string) bool {
+	n := 0
+	for _, r := range w {
+		switch {
+		case unicode.IsLower(r):
+			return false
+		case unicode.IsUpper(r):
+			n++
+		}
+	}
+	return n >= 2
+}
+
+func collapseRuns(w string, max int) string {
+	s := strings.Builder{}
+	var prev rune
+	n := 0
+	for _, r := range w {
+		if unicode.ToLower(r) == unicode.ToLower(prev) && unicode.IsLetter(r) {
+			n++
+		} else {
+			prev = r
+			n = 1
+		}
+		if n <= max {
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
+
+// normalizeRepeats collapses runs of 3 or more letters to 2, and runs of punctuation to 1
+//
+// If collapsing the letters to 1 instead gives a word in the active rules, that's used instead
+// e.g. looooool becomes lol, but gooood becomes good.
+func normalizeRepeats(text string) string {
+	rs := activeRules.Load()
+	words := strings.Fields(text)
+	for i, w := range words {
+		w = bangPat.ReplaceAllStringFunc(w, func(s string) string {
+			switch {
+			case strings.Contains(s, "?") && strings.Contains(s, "!"):
+				return "?!"
+			case strings.Contains(s, "?"):
+				return "?"
+			default:
+				return "!"
+			}
+		})
+		w = dotsPat.ReplaceAllString(w, "...")
+		w = commasPat.ReplaceAllString(w, ",")
+
+		two := collapseRuns(w, 2)
+		if two == w {
+			words[i] = w
+			continue
+		}
+		one := collapseRuns(w, 1)
+		if rs.knows(strings.ToLower(strings.TrimFunc(one, unicode.IsPunct))) {
+			words[i] = one
+		} else {
+			words[i] = two
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// NumberWords spells out the number n
+func NumberWords(n int64) string {
+	switch {
+	case n < 0:
+		return "minus " + NumberWords(-n)
+	case n < 20:
+		return smallNumbers[n]
+	case n < 100:
+		if n%10 == 0 {
+			return tensNumbers[n/10]
+		}
+		return tensNumbers[n/10] + "-" + smallNumbers[n%10]
+	case n < 1000:
+		s := smallNumbers[n/100] + " hundred"
+		if n%100 != 0 {
+			s += " " + NumberWords(n%100)
+		}
+		return s
+	}
+	for _, sc := range scaleNumbers {
+		if n < sc.N {
+			continue
+		}
+		s := NumberWords(n/sc.N) + " " + sc.Name
+		if n%sc.N != 0 {
+			s += " " + NumberWords(n%sc.N)
+		}
+		return s
+	}
+	return ""
+}
+
+// OrdinalWords spells out the ordinal number n e.g. 21 is twenty-first
+func OrdinalWords(n int64) string {
+	s := NumberWords(n)
+	i := strings.LastIndexAny(s, " -") + 1
+	last := s[i:]
+	switch {
+	case ordinalWords[last] != "":
+		last = ordinalWords[last]
+	case strings.HasSuffix(last, "y"):
+		last = last[:len(last)-1] + "ieth"
+	default:
+		last += "th"
+	}
+	return s[:i] + last
+}
+
+// DigitWords spells out each digit in s
+func DigitWords(s string) string {
+	l := make([]string, 0, len(s))
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			l = append(l, smallNumbers[c-'0'])
+		}
+	}
+	return strings.Join(l, " ")
+}
+
+func timeWords(hour, min string, meridiem string) string {
+	h, _ := strconv.ParseInt(hour, 10, 64)
+	m, _ := strconv.ParseInt(min, 10, 64)
+	s := NumberWords(h)
+	switch {
+	case m == 0 && meridiem == "":
+		s += " o'clock"
+	case m == 0:
+	case m < 10:
+		s += " oh " + NumberWords(m)
+	default:
+		s += " " + NumberWords(m)
+	}
+	if meridiem != "" {
+		s += " " + strings.ToUpper(meridiem[:1]) + "M"
+	}
+	return s
+}
+
+// numberWords spells out the number token w, and reports whether it was a number
+func numberWords(w string) (string, bool) {
+	if m := timePat.FindStringSubmatch(w); m != nil {
+		return timeWords(m[1], m[2], m[3]), true
+	}
+	if m := clockPat.FindStringSubmatch(w); m != nil {
+		if h, _ := strconv.Atoi(m[1]); h > 24 {
+			return w, false
+		}
+		if mi, _ := strconv.Atoi(m[2]); mi > 59 {
+			return w, false
+		}
+		return timeWords(m[1], m[2], ""), true
+	}
+	if m := ordinalPat.FindStringSubmatch(w); m != nil {
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || n >= 1e12 {
+			return w, false
+		}
+		return OrdinalWords(n), true
+	}
+	m := numberPat.FindStringSubmatch(w)
+	if m == nil {
+		return w, false
+	}
+	digits := strings.ReplaceAll(m[2], ",", "")
+	n, err := strconv.ParseInt(digits, 10, 64)
+	var s string
+	switch {
+	case err != nil || n >= 1e12 || (len(digits) > 1 && digits[0] == '0') || (len(m[2]) > 7 && m[2] == digits):
+		// phone numbers, IDs, 007, etc. are read digit by digit
+		s = DigitWords(digits)
+	default:
+		s = NumberWords(n)
+	}
+	if m[1] != "" {
+		s = "minus " + s
+	}
+	if m[3] != "" {
+		s += " point " + DigitWords(m[3])
+	}
+	if m[4] != "" {
+		s += " percent"
+	}
+	return s, true
+}
+
+func normalizeNumbers(text string) string {
+	words := strings.Fields(text)
+	wasTime := false
+	for i, w := range words {
+		// keep surrounding punctuation e.g. (1st), 5!
+		j := strings.IndexFunc(w, func(r rune) bool { return !isNumberPunct(r) })
+		k := strings.LastIndexFunc(w, func(r rune) bool { return !isNumberPunct(r) }) + 1
+		if j < 0 {
+			wasTime = false
+			continue
+		}
+		pre, tok, suf := w[:j], w[j:k], w[k:]
+		if strings.HasSuffix(pre, "-") {
+			pre, tok = pre[:len(pre)-1], "-"+tok
+		}
+
+		if wasTime && meridiemPat.MatchString(tok) {
+			words[i] = pre + strings.ToUpper(tok[:1]) + "M" + suf
+			wasTime = false
+			continue
+		}
+
+		s, ok := numberWords(tok)
+		if ok {
+			words[i] = pre + s + suf
+		}
+		wasTime = ok && (clockPat.MatchString(tok) || hourPat.MatchString(tok))
+	}
+	return strings.Join(words, " ")
+}
+
+func isNumberPunct(r rune) bool {
+	return r != '%' && unicode.IsPunct(r)
+}
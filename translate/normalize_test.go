@@ -0,0 +1,74 @@
+package translate
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestNormalizeGolden runs the cases in testdata/normalize.golden
+//
+// Each line is a message and its normalized text, separated by a tab.
+// Blank lines and lines starting with # are ignored.
+// Run `go test -run Golden -update` to rewrite the expected output.
+func TestNormalizeGolden(t *testing.T) {
+	fn := filepath.Join("testdata", "normalize.golden")
+	f, err := os.Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	out := strings.Builder{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		ln := sc.Text()
+		if strings.TrimSpace(ln) == "" || strings.HasPrefix(ln, "#") {
+			out.WriteString(ln + "\n")
+			continue
+		}
+		in, want, _ := strings.Cut(ln, "\t")
+		got := Normalize(config.NormalizeConfig{}, in)
+		out.WriteString(in + "\t" + got + "\n")
+		if !*update && got != want {
+			t.Errorf("%s:%d: Normalize(`%s`): Expected `%s`; Got `%s`", fn, n, in, want, got)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(fn, []byte(out.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNormalizeToggles(t *testing.T) {
+	off := false
+	text := "GREAT GAME!!!!11 2nd 😂 https://x.y soooo"
+	cases := []struct {
+		Cfg  config.NormalizeConfig
+		Want string
+	}{
+		{config.NormalizeConfig{}, "great game! second haha a link soo"},
+		{config.NormalizeConfig{Numbers: &off}, "great game! 2nd haha a link soo"},
+		{config.NormalizeConfig{Links: &off}, "great game! second haha https://x.y soo"},
+		{config.NormalizeConfig{Emoji: &off}, "great game! second 😂 a link soo"},
+		{config.NormalizeConfig{Repeats: &off}, "great game!!!!11 second haha a link soooo"},
+		{config.NormalizeConfig{Caps: &off}, "GREAT GAME! second haha a link soo"},
+	}
+	for _, c := range cases {
+		if got := Normalize(c.Cfg, text); got != c.Want {
+			t.Fatalf("Normalize(%+v): Expected `%s`; Got `%s`", c.Cfg, c.Want, got)
+		}
+	}
+}
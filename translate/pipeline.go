@@ -0,0 +1,386 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/amitybell/srcvox/config"
+)
+
+var (
+	// DefaultPipeline is the list of stages used if Config.Pipeline is empty
+	//
+	// The message is filtered twice, because translations and substitutes
+	// can introduce words that weren't in the original message.
+	// The second time, only the segments that changed are filtered.
+	DefaultPipeline = []string{
+		"markup",
+		"filter",
+		"normalize",
+		"rewrite",
+		"substitute",
+		"name",
+		"translate",
+		"sound",
+		"filter",
+		"speech",
+	}
+
+	// Stages are the transformers available to a Pipeline, keyed by name
+	Stages = map[string]Transformer{}
+)
+
+func init() {
+	for _, t := range []Transformer{
+		markupStage{},
+		filterStage{},
+		textStage{name: "normalize", f: normalizeText},
+		textStage{name: "rewrite", f: rewriteText, keepNames: true},
+		textStage{name: "substitute", f: substituteText, keepNames: true},
+		nameStage{},
+		textStage{name: "translate", f: translateText, keepNames: true},
+		soundStage{},
+		textStage{name: "speech", f: speechText},
+	} {
+		Stages[t.Name()] = t
+	}
+}
+
+// Env is the environment a Pipeline runs in
+type Env struct {
+	Vars      Vars
+	Filter    *Filter
+	Normalize config.NormalizeConfig
+	// IsSound reports whether text is the name of a sound
+	IsSound func(text string) bool
+}
+
+func (env Env) isSound(text string) bool {
+	return env.IsSound != nil && env.IsSound(text)
+}
+
+// Segment is part of a message
+// Either Text is set, or Bleep is true.
+type Segment struct {
+	Text  string `json:"text"`
+	Bleep bool   `json:"bleep"`
+	// Sound is true if Text is the name of a sound to play
+	Sound bool  `json:"sound"`
+	Style Style `json:"style"`
+
+	// filtered is true if Text was already checked by the filter stage
+	filtered bool
+	// expanded is true if Text is a variable expanded by the name stage e.g. the player's name
+	expanded bool
+}
+
+func (seg Segment) isText() bool {
+	return !seg.Bleep && !seg.Sound
+}
+
+type Message struct {
+	Segments []Segment `json:"segments"`
+	Dropped  bool      `json:"dropped"`
+}
+
+// NewMessage returns a message with a single segment containing text
+func NewMessage(text string) Message {
+	return Message{Segments: []Segment{{Text: text}}}
+}
+
+// joined returns a copy of m with adjacent text segments of the same style joined
+func (m Message) joined() Message {
+	segs := make([]Segment, 0, len(m.Segments))
+	for _, seg := range m.Segments {
+		if n := len(segs); n != 0 && seg.isText() && segs[n-1].isText() && segs[n-1].Style == seg.Style {
+			prev := &segs[n-1]
+			prev.Text += " " + seg.Text
+			prev.filtered = prev.filtered && seg.filtered
+			prev.expanded = false
+			continue
+		}
+		segs = append(segs, seg)
+	}
+	m.Segments = segs
+	return m
+}
+
+// String renders the message for display, with bleeps as [bleep] and sounds as [sound:name]
+//
+// Styled segments are rendered with their markup.
+func (m Message) String() string {
+	if m.Dropped {
+		return "[dropped]"
+	}
+	l := make([]string, 0, len(m.Segments))
+	voice := ""
+	for _, seg := range m.Segments {
+		if seg.Style.Voice != voice {
+			voice = seg.Style.Voice
+			l = append(l, "[voice="+voice+"]")
+		}
+		s := seg.Text
+		switch {
+		case seg.Bleep:
+			s = "[bleep]"
+		case seg.Sound:
+			s = "[sound:" + seg.Text + "]"
+		case seg.Text == "":
+			continue
+		}
+		if seg.Style.Slow {
+			s = "~" + s + "~"
+		}
+		if seg.Style.Emphasis {
+			s = "*" + s + "*"
+		}
+		l = append(l, s)
+	}
+	return strings.Join(l, " ")
+}
+
+// Transformer is a stage in a Pipeline
+type Transformer interface {
+	Name() string
+	Transform(env Env, msg Message) Message
+}
+
+// textStage is a Transformer that transforms the text of each segment that isn't a bleep or sound
+type textStage struct {
+	name string
+	f    func(env Env, text string) string
+	// keepNames is true if the stage leaves names expanded by the name stage as-is
+	keepNames bool
+}
+
+func (t textStage) Name() string { return t.name }
+
+func (t textStage) Transform(env Env, msg Message) Message {
+	segs := make([]Segment, 0, len(msg.Segments))
+	for _, seg := range msg.Segments {
+		if !seg.Bleep && !seg.Sound && !(t.keepNames && seg.expanded) {
+			if s := t.f(env, seg.Text); s != seg.Text {
+				seg.Text = s
+				seg.filtered = false
+			}
+		}
+		if seg.Bleep || seg.Text != "" {
+			segs = append(segs, seg)
+		}
+	}
+	msg.Segments = segs
+	return msg
+}
+
+func normalizeText(env Env, text string) string {
+	// sound names are left as-is e.g. weee
+	if env.isSound(text) {
+		return text
+	}
+	return NormalizeText(env.Normalize, text)
+}
+
+func speechText(env Env, text string) string {
+	return NormalizeSpeech(env.Normalize, text)
+}
+
+func rewriteText(env Env, text string) string {
+	for _, rw := range activeRules.Load().rewrites {
+		text, _ = rw.Apply(env.Vars, text)
+	}
+	return text
+}
+
+func substituteText(env Env, text string) string {
+	rs := activeRules.Load()
+	for _, k := range []string{text, strings.ToLower(strings.TrimSpace(text))} {
+		if v, ok := rs.substitutes[k]; ok {
+			return v.Next("")
+		}
+	}
+	return text
+}
+
+// nameStage lowercases each word, and expands the variables in it
+//
+// Values are inserted after lowercasing, so names keep their case.
+// Words with variables are split into their own segment, so later stages don't translate the name.
+type nameStage struct{}
+
+func (nameStage) Name() string { return "name" }
+
+func (nameStage) Transform(env Env, msg Message) Message {
+	vars := env.Vars
+	if vars["name"] == "" {
+		vars = vars.With("name", "someone")
+	}
+
+	segs := make([]Segment, 0, len(msg.Segments))
+	for _, seg := range msg.Segments {
+		if seg.Bleep || seg.Sound || seg.expanded {
+			segs = append(segs, seg)
+			continue
+		}
+
+		var words []string
+		flush := func() {
+			if len(words) != 0 {
+				segs = append(segs, Segment{Text: strings.Join(words, " "), Style: seg.Style})
+				words = nil
+			}
+		}
+		lower := strings.Fields(strings.ToLower(seg.Text))
+		if s := strings.Join(lower, " "); s == seg.Text && vars.Expand(s) == s {
+			segs = append(segs, seg)
+			continue
+		}
+		for _, word := range lower {
+			val := vars.Expand(word)
+			if val == word {
+				words = append(words, word)
+				continue
+			}
+			flush()
+			if val = strings.TrimSpace(val); val != "" {
+				segs = append(segs, Segment{Text: val, Style: seg.Style, expanded: true})
+			}
+		}
+		flush()
+	}
+	msg.Segments = segs
+	return msg
+}
+
+// translateText replaces each word that has a translation
+//
+// Translations are keyed by lowercase words, see nameStage.
+func translateText(env Env, text string) string {
+	rs := activeRules.Load()
+	out := strings.Fields(text)
+	for i, word := range out {
+		out[i] = rs.translations[word].Next(word)
+	}
+	return strings.Join(out, " ")
+}
+
+// soundStage marks the segments that are the name of a sound
+type soundStage struct{}
+
+func (soundStage) Name() string { return "sound" }
+
+func (soundStage) Transform(env Env, msg Message) Message {
+	// segments split by the name stage are joined first, so "hello $name" isn't the sound "hello"
+	msg = msg.joined()
+	for i, seg := range msg.Segments {
+		if !seg.Bleep && !seg.expanded && env.isSound(seg.Text) {
+			msg.Segments[i].Sound = true
+		}
+	}
+	return msg
+}
+
+// filterStage applies Env.Filter to each segment
+type filterStage struct{}
+
+func (filterStage) Name() string { return "filter" }
+
+func (filterStage) Transform(env Env, msg Message) Message {
+	username := env.Vars["username"]
+	if username == "" {
+		username = env.Vars["name"]
+	}
+
+	var segs []Segment
+	for _, seg := range msg.Segments {
+		if seg.Bleep || seg.filtered {
+			segs = append(segs, seg)
+			continue
+		}
+
+		txt := seg.Text
+		if seg.Sound {
+			// sounds are often named after the word they say, with a numeric suffix
+			txt = strings.TrimRightFunc(txt, unicode.IsDigit)
+		}
+		res := env.Filter.Apply(txt)
+		res.Log(username, seg.Text)
+		if res.Dropped {
+			return Message{Dropped: true}
+		}
+
+		if seg.Sound {
+			switch {
+			case len(res.Matches) == 0:
+				seg.filtered = true
+				segs = append(segs, seg)
+			case res.HasBleep():
+				segs = append(segs, Segment{Bleep: true, Style: seg.Style})
+			}
+			continue
+		}
+		for _, fs := range res.Segments {
+			if fs.Bleep || fs.Text != "" {
+				segs = append(segs, Segment{Text: fs.Text, Bleep: fs.Bleep, Style: seg.Style, filtered: true, expanded: seg.expanded})
+			}
+		}
+	}
+	msg.Segments = segs
+	return msg
+}
+
+// Step is the result of a stage in a Pipeline
+type Step struct {
+	Stage   string `json:"stage"`
+	Text    string `json:"text"`
+	Changed bool   `json:"changed"`
+}
+
+// Trace describes the path from a chat message to the spoken message
+type Trace struct {
+	Input  string  `json:"input"`
+	Steps  []Step  `json:"steps"`
+	Output Message `json:"output"`
+}
+
+// Pipeline is an ordered list of stages that turn a chat message into the message to speak
+type Pipeline []Transformer
+
+// NewPipeline returns the pipeline for the named stages
+//
+// If names is empty, DefaultPipeline is used.
+func NewPipeline(names []string) (Pipeline, error) {
+	if len(names) == 0 {
+		names = DefaultPipeline
+	}
+	p := make(Pipeline, 0, len(names))
+	for _, nm := range names {
+		t, ok := Stages[strings.ToLower(strings.TrimSpace(nm))]
+		if !ok {
+			return nil, fmt.Errorf("NewPipeline: Unknown stage `%s`", nm)
+		}
+		p = append(p, t)
+	}
+	return p, nil
+}
+
+// Run runs each stage of the pipeline on text
+//
+// If a stage drops the message, the remaining stages are skipped.
+// Adjacent text segments with the same style are joined in the output, so they're spoken together.
+func (p Pipeline) Run(env Env, text string) Trace {
+	msg := NewMessage(strings.TrimSpace(text))
+	tr := Trace{Input: text}
+	prev := msg.String()
+	for _, t := range p {
+		msg = t.Transform(env, msg)
+		s := msg.String()
+		tr.Steps = append(tr.Steps, Step{Stage: t.Name(), Text: s, Changed: s != prev})
+		prev = s
+		if msg.Dropped {
+			break
+		}
+	}
+	tr.Output = msg.joined()
+	return tr
+}
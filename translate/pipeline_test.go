@@ -0,0 +1,192 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/rng"
+)
+
+func TestPipeline(t *testing.T) {
+	defer SetRules(DefaultRules)
+	SetRules(Rules{
+		Translations: map[string][]string{"u": {"you"}},
+		Substitutes: map[string][]string{
+			"gg":     {"good game $name"},
+			"sounds": {"haha"},
+		},
+	})
+
+	filter, err := NewFilter(config.FilterPolicy{
+		Action: config.FilterMask,
+		Rules: []config.FilterRule{
+			{Pattern: "heck", Action: config.FilterBleep},
+			{Pattern: "nope", Action: config.FilterDrop},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPipeline(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := Env{
+		Vars:    Vars{"name": "KYLE"},
+		Filter:  filter,
+		IsSound: func(s string) bool { return s == "haha" },
+	}
+
+	cases := []struct {
+		Text string
+		Out  string
+	}{
+		{"gg", "good game KYLE"},
+		{"u are 1st!!!", "you are first!"},
+		{"sounds", "[sound:haha]"},
+		{"what the heck u", "what the [bleep] you"},
+		{"nope", "[dropped]"},
+	}
+	for _, c := range cases {
+		if s := p.Run(env, c.Text).Output.String(); s != c.Out {
+			t.Fatalf("Run(`%s`): Expected `%s`; Got `%s`", c.Text, c.Out, s)
+		}
+	}
+
+	// the steps report each stage, and whether it changed the message
+	tr := p.Run(env, "gg")
+	if len(tr.Steps) != len(DefaultPipeline) {
+		t.Fatalf("Expected %d steps; Got %d", len(DefaultPipeline), len(tr.Steps))
+	}
+	for _, st := range tr.Steps {
+		want := st.Stage == "substitute" || st.Stage == "name"
+		if st.Changed != want {
+			t.Fatalf("Step `%s`: Expected changed=%v; Got %v (`%s`)", st.Stage, want, st.Changed, st.Text)
+		}
+	}
+
+	// markup, then filter
+	if tr := p.Run(env, "nope"); len(tr.Steps) != 2 || tr.Steps[1].Stage != "filter" {
+		t.Fatalf("Expected dropped message to stop the pipeline; Got %d steps", len(tr.Steps))
+	}
+}
+
+func TestPipelineConfig(t *testing.T) {
+	if _, err := NewPipeline([]string{"filter", "nonsense"}); err == nil {
+		t.Fatal("Expected unknown stage to fail")
+	}
+
+	p, err := NewPipeline([]string{"translate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Run(Env{}, "gg 2nd").Output.String(); s != "good game 2nd" {
+		t.Fatalf("Expected only translations to be applied; Got `%s`", s)
+	}
+}
+
+func TestTranslateDefault(t *testing.T) {
+	cases := map[string]string{
+		"i <3 this": "I love this",
+		"i am here": "I am here",
+	}
+	for in, want := range cases {
+		if got := Translate(nil, in); got != want {
+			t.Fatalf("Translate(`%s`): Expected `%s`; Got `%s`", in, want, got)
+		}
+	}
+}
+
+// baselineTranslate is Translate as it was before the pipeline
+func baselineTranslate(vars Vars, text string) string {
+	rs := activeRules.Load()
+	for _, rw := range rs.rewrites {
+		text, _ = rw.Apply(vars, text)
+	}
+	for _, k := range []string{text, strings.ToLower(strings.TrimSpace(text))} {
+		if v, ok := rs.substitutes[k]; ok {
+			text = v.Next("")
+			break
+		}
+	}
+	if vars["name"] == "" {
+		vars = vars.With("name", "someone")
+	}
+	out := strings.Fields(strings.ToLower(text))
+	for i, word := range out {
+		word = vars.Expand(word)
+		out[i] = rs.translations[word].Next(word)
+	}
+	return strings.TrimSpace(strings.Join(out, " "))
+}
+
+func TestPipelineBaseline(t *testing.T) {
+	defer rng.SetSource(nil)
+	defer SetRules(DefaultRules)
+
+	p, err := NewPipeline(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []string{
+		"gg",
+		"GG",
+		"hacker",
+		"brb guys",
+		"Im back",
+		"np man",
+		"$name wins",
+		"$NAME wins",
+		"ladydecade",
+		"Thx for the game",
+		"Nice Shot $name",
+	}
+	for _, vars := range []Vars{nil, {"name": "KYLE"}, {"name": "GG EZ"}, {"name": "gg ez"}} {
+		for _, in := range inputs {
+			rng.Seed(1)
+			SetRules(DefaultRules)
+			want := baselineTranslate(vars, in)
+
+			rng.Seed(1)
+			SetRules(DefaultRules)
+			got := p.Run(Env{Vars: vars}, in).Output.String()
+			if got != want {
+				t.Fatalf("Run(%v, `%s`): Expected `%s`; Got `%s`", vars, in, want, got)
+			}
+		}
+	}
+}
+
+func TestPipelineFilterOnce(t *testing.T) {
+	defer SetRules(DefaultRules)
+	SetRules(Rules{
+		Translations: map[string][]string{"u": {"you"}},
+		Substitutes:  map[string][]string{"gg": {"heck yeah"}},
+	})
+	filter, err := NewFilter(config.FilterPolicy{
+		Action: config.FilterBleep,
+		Rules:  []config.FilterRule{{Pattern: "heck"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := Env{Filter: filter}
+
+	msg := Stages["filter"].Transform(env, NewMessage("u are ok"))
+	msg = Stages["normalize"].Transform(env, msg)
+	if !msg.Segments[0].filtered {
+		t.Fatalf("Expected unchanged segments to stay filtered; Got %+v", msg.Segments)
+	}
+	msg = Stages["translate"].Transform(env, msg)
+	if msg.Segments[0].filtered {
+		t.Fatalf("Expected changed segments to be filtered again; Got %+v", msg.Segments)
+	}
+
+	// words introduced after the first filter are still filtered
+	p, _ := NewPipeline(nil)
+	if s := p.Run(env, "gg").Output.String(); s != "[bleep] yeah" {
+		t.Fatalf("Expected the substitute to be filtered; Got `%s`", s)
+	}
+}
@@ -0,0 +1,104 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	varPat = regexp.MustCompile(`\$([a-zA-Z_]+)`)
+)
+
+// Vars are the template variables available to substitutes, rewrites and chat messages
+//
+// The following are set by the app:
+//
+//	$name: the speakable name of the player that sent the message
+//	$username: the username of the player that sent the message
+//	$map: the map the server is running
+//	$server: the name of the server
+//	$players: the number of players on the server
+//	$random_player: the name of a random player on the server
+//	$time: the local time
+//	$game: the title of the game
+type Vars map[string]string
+
+// With returns a copy of v with k set to val
+func (v Vars) With(k, val string) Vars {
+	p := make(Vars, len(v)+1)
+	for k, val := range v {
+		p[k] = val
+	}
+	p[k] = val
+	return p
+}
+
+// Expand replaces the variables in s that are set in v
+func (v Vars) Expand(s string) string {
+	return v.expand(s, func(s string) string { return s })
+}
+
+// expandTemplate is like Expand, but escapes the values for use in a regexp replacement template
+func (v Vars) expandTemplate(s string) string {
+	return v.expand(s, func(s string) string { return strings.ReplaceAll(s, "$", "$$") })
+}
+
+func (v Vars) expand(s string, esc func(string) string) string {
+	if len(v) == 0 || !strings.Contains(s, "$") {
+		return s
+	}
+	return varPat.ReplaceAllStringFunc(s, func(m string) string {
+		if val, ok := v[strings.ToLower(m[1:])]; ok {
+			return esc(val)
+		}
+		return m
+	})
+}
+
+// Rewrite is a rule that rewrites messages matching Pattern into Replace
+//
+// Replace may reference the capture groups of Pattern using $1, ${1} or ${name},
+// as well as any of the variables in Vars.
+type Rewrite struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+
+	re *regexp.Regexp
+}
+
+func (rw Rewrite) compile() (Rewrite, error) {
+	re, err := regexp.Compile(rw.Pattern)
+	if err != nil {
+		return rw, fmt.Errorf("Rewrite(`%s`): %w", rw.Pattern, err)
+	}
+	rw.re = re
+	return rw, nil
+}
+
+// Apply rewrites text, and reports whether the pattern matched
+func (rw Rewrite) Apply(vars Vars, text string) (string, bool) {
+	re := rw.re
+	if re == nil {
+		var err error
+		if re, err = regexp.Compile(rw.Pattern); err != nil {
+			return text, false
+		}
+	}
+	if !re.MatchString(text) {
+		return text, false
+	}
+	return re.ReplaceAllString(text, vars.expandTemplate(rw.Replace)), true
+}
+
+func compileRewrites(l []Rewrite) ([]Rewrite, error) {
+	out := make([]Rewrite, 0, len(l))
+	for _, rw := range l {
+		rw, err := rw.compile()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rw)
+	}
+	return out, nil
+}
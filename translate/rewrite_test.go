@@ -0,0 +1,55 @@
+package translate
+
+import (
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	vars := Vars{
+		"name":    "KYLE",
+		"map":     "dm_lockdown",
+		"players": "$5",
+	}
+	cases := []struct {
+		Pattern string
+		Replace string
+		Text    string
+		Out     string
+		Ok      bool
+	}{
+		{`(\d+)v(\d+)`, `$1 versus $2`, `5v5 me`, `5 versus 5 me`, true},
+		{`^where\b.*`, `we're on $map`, `where r u`, `we're on dm_lockdown`, true},
+		{`^count$`, `${0} $players`, `count`, `count $5`, true},
+		{`^(?P<who>\w+) sucks$`, `${who} is great, says $name`, `bob sucks`, `bob is great, says KYLE`, true},
+		{`^x$`, `y`, `nope`, `nope`, false},
+	}
+	for _, c := range cases {
+		rw, err := Rewrite{Pattern: c.Pattern, Replace: c.Replace}.compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, ok := rw.Apply(vars, c.Text)
+		if out != c.Out || ok != c.Ok {
+			t.Fatalf("Rewrite(`%s` => `%s`).Apply(`%s`): Expected `%s`, %v; Got `%s`, %v", c.Pattern, c.Replace, c.Text, c.Out, c.Ok, out, ok)
+		}
+	}
+}
+
+func TestTranslateVars(t *testing.T) {
+	defer SetRules(DefaultRules)
+
+	SetRules(DefaultRules.Merge(Rules{
+		Substitutes: map[string][]string{"hacker": {"$name is the hacker on $map!"}},
+	}))
+
+	vars := Vars{"name": "KYLE", "map": "dm_lockdown"}
+	if s := Translate(vars, "hacker"); s != "KYLE is the hacker on dm_lockdown!" {
+		t.Fatalf("Expected `KYLE is the hacker on dm_lockdown!`; Got `%s`", s)
+	}
+	if s := Translate(nil, "hacker"); s != "someone is the hacker on $map!" {
+		t.Fatalf("Expected `someone is the hacker on $map!`; Got `%s`", s)
+	}
+	if s := Translate(vars, "rematch 2v2"); s != "rematch 2 versus 2" {
+		t.Fatalf("Expected `rematch 2 versus 2`; Got `%s`", s)
+	}
+}
@@ -0,0 +1,178 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/amitybell/srcvox/files"
+)
+
+var (
+	DefaultRules = MustParseRules(files.DefaultRules)
+
+	activeRules = func() *atomic.Pointer[ruleSet] {
+		p := &atomic.Pointer[ruleSet]{}
+		p.Store(newRuleSet(DefaultRules))
+		return p
+	}()
+)
+
+// Rules are the translation and substitution rules used by Translate
+//
+// Keys may be a comma or space separated list of words, all of which share the same alternatives.
+type Rules struct {
+	// Translations are applied to each word of a message
+	Translations map[string][]string `json:"translations"`
+
+	// Substitutes are applied to the whole message
+	// Each alternative is either the name of a sound, or a phrase to speak.
+	Substitutes map[string][]string `json:"substitutes"`
+
+	// Rewrites are applied, in order, to the whole message before substitutes
+	Rewrites []Rewrite `json:"rewrites"`
+}
+
+type ruleSet struct {
+	rules        Rules
+	translations map[string]*Alt[string]
+	substitutes  map[string]*Alt[string]
+	rewrites     []Rewrite
+}
+
+func newRuleSet(r Rules) *ruleSet {
+	rs := &ruleSet{
+		rules:        r,
+		translations: AltMap(r.Translations),
+		substitutes:  AltMap(r.Substitutes),
+	}
+	for _, rw := range r.Rewrites {
+		// invalid rules are rejected by ParseRules, so this only affects rules set directly
+		if rw, err := rw.compile(); err == nil {
+			rs.rewrites = append(rs.rewrites, rw)
+		}
+	}
+	return rs
+}
+
+// knows reports whether word has a translation or substitute
+func (rs *ruleSet) knows(word string) bool {
+	return rs.translations[word] != nil || rs.substitutes[word] != nil
+}
+
+func expandRules(p map[string][]string) map[string][]string {
+	q := make(map[string][]string, len(p))
+	for k, v := range p {
+		for _, k := range strings.FieldsFunc(k, commaSpace) {
+			q[k] = v
+		}
+	}
+	return q
+}
+
+func mergeRules(p, q map[string][]string) map[string][]string {
+	m := expandRules(p)
+	for k, v := range expandRules(q) {
+		if len(v) == 0 {
+			delete(m, k)
+		} else {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Merge returns the rules in r overridden by the rules in p
+//
+// Keys in p replace the same keys in r. A key with an empty list removes it.
+// Rewrites in p are applied before those in r.
+func (r Rules) Merge(p Rules) Rules {
+	return Rules{
+		Translations: mergeRules(r.Translations, p.Translations),
+		Substitutes:  mergeRules(r.Substitutes, p.Substitutes),
+		Rewrites:     append(append([]Rewrite(nil), p.Rewrites...), r.Rewrites...),
+	}
+}
+
+// Expand returns a copy of r with each key list expanded into separate keys
+func (r Rules) Expand() Rules {
+	return Rules{
+		Translations: expandRules(r.Translations),
+		Substitutes:  expandRules(r.Substitutes),
+		Rewrites:     r.Rewrites,
+	}
+}
+
+// SubstituteNames returns the sorted list of words that have substitutes
+func (r Rules) SubstituteNames() []string {
+	m := expandRules(r.Substitutes)
+	l := make([]string, 0, len(m))
+	for k := range m {
+		l = append(l, k)
+	}
+	sort.Strings(l)
+	return l
+}
+
+func ParseRules(s []byte) (Rules, error) {
+	var r Rules
+	if err := json.Unmarshal(s, &r); err != nil {
+		return r, fmt.Errorf("ParseRules: %w", err)
+	}
+	if _, err := compileRewrites(r.Rewrites); err != nil {
+		return r, fmt.Errorf("ParseRules: %w", err)
+	}
+	return r, nil
+}
+
+func MustParseRules(s []byte) Rules {
+	r, err := ParseRules(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ReadRules reads the user rules file fn
+//
+// It's not an error if the file doesn't exist
+func ReadRules(fn string) (Rules, error) {
+	s, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return Rules{}, nil
+	}
+	if err != nil {
+		return Rules{}, fmt.Errorf("ReadRules: %w", err)
+	}
+	r, err := ParseRules(s)
+	if err != nil {
+		return Rules{}, fmt.Errorf("ReadRules: %s: %w", fn, err)
+	}
+	return r, nil
+}
+
+// LoadRules reads the user rules file fn, merges it with DefaultRules and makes them active
+//
+// If the file cannot be read, the active rules are left unchanged.
+func LoadRules(fn string) (Rules, error) {
+	usr, err := ReadRules(fn)
+	if err != nil {
+		return ActiveRules(), err
+	}
+	r := DefaultRules.Merge(usr)
+	SetRules(r)
+	return r, nil
+}
+
+// SetRules replaces the active rules
+func SetRules(r Rules) {
+	activeRules.Store(newRuleSet(r))
+}
+
+// ActiveRules returns the rules currently used by Translate
+func ActiveRules() Rules {
+	return activeRules.Load().rules
+}
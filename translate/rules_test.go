@@ -0,0 +1,62 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRulesMerge(t *testing.T) {
+	def := Rules{
+		Substitutes: map[string][]string{
+			"bji, bij": {"bitch1"},
+			"gg":       {"good game"},
+		},
+	}
+	usr := Rules{
+		Substitutes: map[string][]string{
+			"bij": {},
+			"gg":  {"GG"},
+			"wp":  {"well played"},
+		},
+	}
+	r := def.Merge(usr)
+
+	cases := map[string][]string{
+		"bji": {"bitch1"},
+		"bij": nil,
+		"gg":  {"GG"},
+		"wp":  {"well played"},
+	}
+	for k, want := range cases {
+		got := r.Substitutes[k]
+		if len(got) != len(want) || (len(want) != 0 && got[0] != want[0]) {
+			t.Fatalf("Substitutes[%s]: Expected `%v`; Got `%v`", k, want, got)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	defer SetRules(DefaultRules)
+
+	fn := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(fn, []byte(`{"substitutes": {"gg": ["well played"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRules(fn); err != nil {
+		t.Fatal(err)
+	}
+	if s := Translate(nil, "gg"); s != "well played" {
+		t.Fatalf("Expected `well played`; Got `%s`", s)
+	}
+
+	if err := os.WriteFile(fn, []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRules(fn); err == nil {
+		t.Fatal("Expected invalid rules to fail")
+	}
+	if s := Translate(nil, "gg"); s != "well played" {
+		t.Fatalf("Expected active rules to be unchanged; Got `%s`", s)
+	}
+}
@@ -3,91 +3,52 @@ package translate
 import (
 	"regexp"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/amitybell/srcvox/rng"
 )
 
 var (
-	Translations = AltMap(map[string][]string{
-		"<3":  {"love"},
-		"i":   {"I"},
-		"im":  {"I'm"},
-		"sry": {"sorry"},
-		"bb":  {"bye"},
-		"brb": {"back", "I'll be right back"},
-		"gl":  {"good luck!"},
-		"bbq": {"barbeque"},
-		"hf":  {"have fun!"},
-		"kfc": {"KFC", "kfc"},
-		"np":  {"no problem", "no worries", "shit happens"},
-		"ns":  {"nice shot"},
-		"ko":  {"KO", "knock out"},
-		"wb":  {"welcome back"},
-		"icu": {"I see you!"},
-		"gg":  {"good game"},
-		"thx": {"thanks"},
-		":)":  {""},
-		":(":  {""},
-		":d":  {""},
-		"xd":  {""},
-		"ez":  {"easy"},
-		"ftw": {"for the win"},
-		"jk":  {"just kidding"},
-		"btw": {"by the way"},
-	})
-
-	Substites = AltMap(map[string][]string{
-		"ns":              {"nice shot", "goodjob", "decent"},
-		"bb":              {"bye1", "bye-ni1", "bye-ni2"},
-		"bye":             {"bye1", "bye-ni1", "bye-ni2"},
-		"glhf":            {"good luck, have fun!"},
-		"gg":              {"GG", "good game", "game", "good", "nice game", "well played"},
-		"icu":             {"I see you!", "iseeyou"},
-		"icq":             {"list", "look", "honey", "run2"},
-		"yw":              {"you're welcome!"},
-		"hacker":          {"hacker1", "$name is the hacker!"},
-		"bji, bij, bitch": {"bitch1"},
-		"hack":            {"hack", "hack the planet!"},
-		"usure":           {"I'm sure"},
-		"boxxy":           {"isboxxy", "nottrollin", "iamboxxy"},
-		"baby":            {"corner"},
-		"lol":             {"haha", "lol"},
-		"ladydecade":      {"ladydecade1", "ladydecade2", "ladydecade3", "ladydecade4"},
-		"zombie":          {"zombie1", "zombie2"},
-		"dust":            {"dust1", "dust2"},
-		"drunken":         {"drunken1", "drunken2", "drunken2", "drunken4", "drunken5", "drunken6", "drunken7"},
-		"run":             {"run1", "run2"},
-		"shit":            {"shit1", "shit2", "shit3", "shit4"},
-		"THX":             {"THX"},
-		"thx":             {"thanks"},
-		"wololo":          {"wololo1", "wololo2"},
-		"gingle":          {"jinglebell"},
-		"wambulance":      {"wambulance1", "wambulance2"},
-		"happynewyear":    {"newyear"},
-		"lying":           {"lying1", "bjork1"},
-		"lala":            {"daria1"},
-		"shallow":         {"daria2"},
-		"wow":             {"daria3"},
-		"nmchance":        {"daria4"},
-		"killyou":         {"daria5"},
-		"shootme":         {"daria6"},
-		"tyvm":            {"daria7"},
-		"happycat":        {"happycat1", "happycat2"},
-	})
-
-	clanNamePat = regexp.MustCompile(`^\s*((?:\*+\s*[^*]+\*+)|(?:\[+\s*[^]]+\]+)|(?:\(+\s*[^)]+\)+))\s*(.+?)\s*$`)
+	clanNamePat = regexp.MustCompile(`^\s*(` + strings.Join([]string{
+		`\*+\s*[^*]+\*+`,
+		`\[+\s*[^]]+\]+`,
+		`\(+\s*[^)]+\)+`,
+		`\{+\s*[^}]+\}+`,
+		`\|+\s*[^|]+\|+`,
+		`=+\s*[^=]+=+`,
+		`[^\s|]+\|+`,
+	}, "|") + `)\s*(.+?)\s*$`)
 )
 
+// Alt rotates through the alternatives in L
+//
+// L is shuffled on the first call to Next, rather than when the rules are loaded,
+// so a seed installed by rng.Seed after the rules are loaded still applies.
+// Next is safe for concurrent use.
 type Alt[T any] struct {
 	L []T
 	i int
+
+	mu       sync.Mutex
+	shuffled bool
 }
 
 func (a *Alt[T]) Next(def T) (v T) {
-	if a == nil || len(a.L) == 0 {
+	if a == nil {
+		return def
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.L) == 0 {
 		return def
 	}
+	if !a.shuffled {
+		a.L = rng.Shuffle(a.L)
+		a.shuffled = true
+	}
 	v = a.L[a.i]
 	a.i = (a.i + 1) % len(a.L)
 	return v
@@ -97,7 +58,7 @@ func AltMap[T any](p map[string][]T) map[string]*Alt[T] {
 	q := make(map[string]*Alt[T], len(p))
 	for k, v := range p {
 		for _, k := range strings.FieldsFunc(k, commaSpace) {
-			q[k] = &Alt[T]{L: rng.Shuffle(v)}
+			q[k] = &Alt[T]{L: v}
 		}
 	}
 	return q
@@ -107,28 +68,16 @@ func commaSpace(r rune) bool {
 	return r == ',' || unicode.IsSpace(r)
 }
 
-func Translate(name, text string) string {
-	for _, k := range []string{text, strings.ToLower(strings.TrimSpace(text))} {
-		if v, ok := Substites[k]; ok {
-			text = v.Next("")
-			break
-		}
-	}
-
-	if name == "" {
-		name = "someone"
+// Translate converts a chat message into the text to speak or the name of a sound to play
+//
+// It runs the rewrite, substitute, name and translate stages of the pipeline.
+func Translate(vars Vars, text string) string {
+	env := Env{Vars: vars}
+	msg := NewMessage(strings.TrimSpace(text))
+	for _, nm := range []string{"rewrite", "substitute", "name", "translate"} {
+		msg = Stages[nm].Transform(env, msg)
 	}
-
-	out := strings.Fields(strings.ToLower(text))
-	for i, word := range out {
-		switch word {
-		case "$name":
-			word = name
-		}
-		out[i] = Translations[word].Next(word)
-	}
-
-	return strings.TrimSpace(strings.Join(out, " "))
+	return msg.String()
 }
 
 func ClanName(username string) (clan, name string) {
@@ -1,7 +1,10 @@
 package translate
 
 import (
+	"slices"
 	"testing"
+
+	"github.com/amitybell/srcvox/rng"
 )
 
 func TestClanName(t *testing.T) {
@@ -14,6 +17,12 @@ func TestClanName(t *testing.T) {
 		{"[PP] TEH PWNERER", "[PP]", "TEH PWNERER"},
 		{"(PP) ★TAGI★", "(PP)", "★TAGI★"},
 		{"KYLE", "", "KYLE"},
+		{"|PP| KYLE", "|PP|", "KYLE"},
+		{"{PP}KYLE", "{PP}", "KYLE"},
+		{"=PP= KYLE", "=PP=", "KYLE"},
+		{"PP| KYLE", "PP|", "KYLE"},
+		{"PP|KYLE", "PP|", "KYLE"},
+		{"KYLE =)", "", "KYLE =)"},
 	}
 
 	for _, c := range cases {
@@ -26,3 +35,44 @@ func TestClanName(t *testing.T) {
 		}
 	}
 }
+
+func TestAltSeeded(t *testing.T) {
+	defer rng.SetSource(nil)
+
+	next := func() []string {
+		a := AltMap(map[string][]string{"gg": {"a", "b", "c", "d", "e"}})["gg"]
+		l := make([]string, 6)
+		for i := range l {
+			l[i] = a.Next("")
+		}
+		return l
+	}
+
+	rng.Seed(1)
+	a := next()
+	rng.Seed(1)
+	b := next()
+	if !slices.Equal(a, b) {
+		t.Fatalf("Expected the same seed to give the same rotation; Got %v and %v", a, b)
+	}
+	if a[0] != a[5] {
+		t.Fatalf("Expected alternatives to rotate; Got %v", a)
+	}
+}
+
+func TestAltConcurrent(t *testing.T) {
+	a := AltMap(map[string][]string{"gg": {"a", "b", "c"}})["gg"]
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for j := 0; j < 100; j++ {
+				if s := a.Next(""); s == "" {
+					t.Error("Expected an alternative")
+				}
+			}
+		}()
+	}
+	<-done
+	<-done
+}
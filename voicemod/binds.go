@@ -0,0 +1,204 @@
+package voicemod
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BindWord = `#sv`
+
+	bindsPrevKey = "/voicemod/binds/prev"
+
+	// bindQueryTimeout is how long to wait for the reply to a `bind <key>` query before sending it again
+	bindQueryTimeout = 5 * time.Second
+)
+
+var (
+	BindPat        = regexp.MustCompile(`^#sv\s+(.+?)\s*$`)
+	BindUnboundPat = regexp.MustCompile(`^"([^"]+)" is not bound$`)
+)
+
+// keyBinds manages the in-game binds generated from Config.Binds.
+//
+// Before a key is bound, its current bind is queried with `bind <key>` and saved
+// so it can be restored when we disconnect or shut down.
+// The saved binds are also persisted in the store, so that if we crash,
+// we don't mistake our own binds for the user's on the next start.
+// Queries whose reply is lost are sent again by the next apply after bindQueryTimeout.
+type keyBinds struct {
+	vm  *voiceMod
+	now func() time.Time
+
+	mu      sync.Mutex
+	applied map[string]string
+	pending map[string]pendingBind
+	prev    map[string]string
+}
+
+// pendingBind is a key waiting for the reply to its `bind <key>` query
+type pendingBind struct {
+	target string
+	sent   time.Time
+}
+
+func newKeyBinds(vm *voiceMod) *keyBinds {
+	kb := &keyBinds{
+		vm:      vm,
+		now:     time.Now,
+		applied: map[string]string{},
+		pending: map[string]pendingBind{},
+		prev:    map[string]string{},
+	}
+	return kb
+}
+
+func bindKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+func bindTarget(target string) string {
+	target = strings.Map(func(r rune) rune {
+		switch r {
+		case '"', ';', '\r', '\n':
+			return -1
+		}
+		return r
+	}, target)
+	return strings.TrimSpace(target)
+}
+
+func bindCommand(target string) string {
+	return `echo ` + BindWord + ` ` + target
+}
+
+func isOurBind(cmd string) bool {
+	return strings.HasPrefix(strings.TrimSpace(cmd), `echo `+BindWord)
+}
+
+func (kb *keyBinds) savePrev() {
+	if err := kb.vm.app.Store().Put(bindsPrevKey, kb.prev); err != nil {
+		kb.vm.app.Logs().Println("keyBinds.savePrev:", err)
+	}
+}
+
+func (kb *keyBinds) storedPrev(key string) string {
+	var m map[string]string
+	kb.vm.app.Store().Get(bindsPrevKey, &m)
+	return m[key]
+}
+
+func (kb *keyBinds) bind(key, target string) {
+	if err := kb.vm.Exec(X{"bind", key, bindCommand(target)}); err != nil {
+		kb.vm.app.Logs().Println("keyBinds.bind:", err)
+		return
+	}
+	kb.applied[key] = target
+}
+
+func (kb *keyBinds) restoreKey(key string) {
+	prev := kb.prev[key]
+	cmd := X{"bind", key, prev}
+	if prev == "" {
+		cmd = X{"unbind", key}
+	}
+	if err := kb.vm.Exec(cmd); err != nil {
+		kb.vm.app.Logs().Println("keyBinds.restore:", err)
+	}
+	delete(kb.applied, key)
+	delete(kb.prev, key)
+}
+
+// apply binds the keys in want, and restores any previously applied keys that are no longer wanted
+func (kb *keyBinds) apply(want map[string]string) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	wantKeys := make(map[string]string, len(want))
+	for key, target := range want {
+		key, target = bindKey(key), bindTarget(target)
+		if key == "" || target == "" {
+			continue
+		}
+		wantKeys[key] = target
+	}
+
+	changed := false
+	for key := range kb.applied {
+		if _, ok := wantKeys[key]; !ok {
+			kb.restoreKey(key)
+			changed = true
+		}
+	}
+	for key := range kb.pending {
+		if _, ok := wantKeys[key]; !ok {
+			delete(kb.pending, key)
+		}
+	}
+	now := kb.now()
+	for key, target := range wantKeys {
+		pb, isPending := kb.pending[key]
+		switch {
+		case kb.applied[key] == target:
+		case isPending && now.Sub(pb.sent) < bindQueryTimeout:
+			pb.target = target
+			kb.pending[key] = pb
+		case kb.hasPrev(key):
+			kb.bind(key, target)
+		default:
+			// the key wasn't queried yet, or the reply was lost
+			kb.pending[key] = pendingBind{target: target, sent: now}
+			if err := kb.vm.Exec(X{"bind", key}); err != nil {
+				kb.vm.app.Logs().Println("keyBinds.apply:", err)
+				delete(kb.pending, key)
+			}
+		}
+	}
+	if changed {
+		kb.savePrev()
+	}
+}
+
+func (kb *keyBinds) hasPrev(key string) bool {
+	_, ok := kb.prev[key]
+	return ok
+}
+
+// readBind handles the reply to a `bind <key>` query
+// it reports whether the reply was expected
+func (kb *keyBinds) readBind(key, cmd string) bool {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	key = bindKey(key)
+	pb, ok := kb.pending[key]
+	if !ok {
+		return false
+	}
+	delete(kb.pending, key)
+
+	if isOurBind(cmd) {
+		cmd = kb.storedPrev(key)
+	}
+	kb.prev[key] = cmd
+	kb.savePrev()
+	kb.bind(key, pb.target)
+	return true
+}
+
+// restore restores the user's binds for all the keys we've bound
+func (kb *keyBinds) restore() {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	if len(kb.applied) == 0 && len(kb.pending) == 0 {
+		return
+	}
+	for key := range kb.applied {
+		kb.restoreKey(key)
+	}
+	clear(kb.pending)
+	kb.savePrev()
+}
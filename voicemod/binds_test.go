@@ -0,0 +1,128 @@
+package voicemod
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/store"
+	"golang.org/x/time/rate"
+)
+
+// fakeConsole is a Conn that records the commands written to it
+type fakeConsole struct {
+	bytes.Buffer
+}
+
+func (fc *fakeConsole) ReadString(byte) (string, error) { return "", nil }
+func (fc *fakeConsole) Close() error                    { return nil }
+
+// cmds returns the commands written since the last call
+func (fc *fakeConsole) cmds() []string {
+	s := strings.TrimSuffix(fc.String(), "\r\n")
+	fc.Reset()
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\r\n")
+}
+
+// storeApp is a fakeApp with a store, and rate limiters like the app's
+type storeApp struct {
+	*fakeApp
+	db       *store.DB
+	limiters map[string]*rate.Limiter
+}
+
+func (sa *storeApp) Store() *store.DB { return sa.db }
+
+func (sa *storeApp) Limiter(name string) *rate.Limiter {
+	if sa.limiters == nil {
+		sa.limiters = map[string]*rate.Limiter{}
+	}
+	lim, ok := sa.limiters[name]
+	if !ok {
+		lim = rate.NewLimiter(rate.Every(sa.state.RateLimit.D), 1)
+		sa.limiters[name] = lim
+	}
+	return lim
+}
+
+func TestKeyBinds(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fc := &fakeConsole{}
+	vm := &voiceMod{Conn: fc, app: &storeApp{fakeApp: newFakeApp(t, "alice"), db: db}}
+	vm.binds = newKeyBinds(vm)
+	now := time.Now()
+	vm.binds.now = func() time.Time { return now }
+
+	check := func(what string, exp ...string) {
+		t.Helper()
+		if l := fc.cmds(); !slices.Equal(l, exp) {
+			t.Fatalf("%s: Expected commands %q; Got %q", what, exp, l)
+		}
+	}
+	reply := func(line string) {
+		t.Helper()
+		if err := vm.readLine(line); err != nil {
+			t.Fatalf("readLine(`%s`): %s", line, err)
+		}
+	}
+
+	vm.binds.apply(map[string]string{"F1": "hello"})
+	check("apply", `bind f1`)
+	vm.binds.apply(map[string]string{"F1": "hello"})
+	check("apply while pending")
+	reply(`"f1" = "+jump"`)
+	check("read", `bind f1 "echo #sv hello"`)
+	vm.binds.apply(map[string]string{"F1": "hello"})
+	check("apply when bound")
+
+	// the reply to the f2 query is lost
+	vm.binds.apply(map[string]string{"F1": "hello", "F2": "gg"})
+	check("apply f2", `bind f2`)
+	now = now.Add(bindQueryTimeout)
+	vm.binds.apply(map[string]string{"F1": "hello", "F2": "gg"})
+	check("re-query f2", `bind f2`)
+	reply(`"f2" is not bound`)
+	check("read f2", `bind f2 "echo #sv gg"`)
+
+	// non-ASCII targets are restored as they were
+	vm.binds.apply(map[string]string{"F1": "hello", "F2": "gg", "F3": `say "héllo"`})
+	check("apply f3", `bind f3`)
+	reply(`"f3" = "say héllo wörld"`)
+	check("read f3", `bind f3 "echo #sv say héllo"`)
+
+	vm.binds.restore()
+	l := fc.cmds()
+	slices.Sort(l)
+	if exp := []string{`bind f1 +jump`, `bind f3 "say héllo wörld"`, `unbind f2`}; !slices.Equal(l, exp) {
+		t.Fatalf("restore: Expected commands %q; Got %q", exp, l)
+	}
+	if len(vm.binds.applied) != 0 || len(vm.binds.pending) != 0 {
+		t.Fatalf("Expected no binds after restore; Got applied=%v, pending=%v", vm.binds.applied, vm.binds.pending)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"+jump":        `+jump`,
+		"héllo":        `héllo`,
+		"say héllo":    `"say héllo"`,
+		`say "hi"`:     `"say 'hi'"`,
+		"a;b":          `"a;b"`,
+		"line1\nline2": `"line1 line2"`,
+	}
+	for s, exp := range tests {
+		if q := quote(s); q != exp {
+			t.Errorf("quote(%q): Expected `%s`; Got `%s`", s, exp, q)
+		}
+	}
+}
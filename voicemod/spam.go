@@ -0,0 +1,289 @@
+package voicemod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/amitybell/srcvox/appstate"
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/store"
+)
+
+const (
+	mutesKey = "/voicemod/mutes"
+
+	// maxSimilarityLen limits the cost of comparing long messages
+	maxSimilarityLen = 160
+)
+
+var (
+	mutesMu sync.Mutex
+)
+
+// Mute is a player that was automatically muted by the spam detector
+type Mute struct {
+	// Key is the player's steam ID, or username if the ID isn't known
+	Key      string    `json:"key"`
+	Username string    `json:"username"`
+	Reason   string    `json:"reason"`
+	Until    time.Time `json:"until"`
+}
+
+func readMutes(db *store.DB, now time.Time) map[string]Mute {
+	var m map[string]Mute
+	db.Get(mutesKey, &m)
+	for k, mu := range m {
+		if !now.Before(mu.Until) {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+// Mutes returns the players that are currently muted
+func Mutes(db *store.DB) []Mute {
+	mutesMu.Lock()
+	defer mutesMu.Unlock()
+
+	m := readMutes(db, time.Now())
+	l := make([]Mute, 0, len(m))
+	for _, mu := range m {
+		l = append(l, mu)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Until.Before(l[j].Until) })
+	return l
+}
+
+// Unmute removes the mute for the player key
+func Unmute(db *store.DB, key string) error {
+	mutesMu.Lock()
+	defer mutesMu.Unlock()
+
+	m := readMutes(db, time.Now())
+	if _, ok := m[key]; !ok {
+		return fmt.Errorf("Unmute(%s): Not muted", key)
+	}
+	delete(m, key)
+	if err := db.Put(mutesKey, m); err != nil {
+		return fmt.Errorf("Unmute(%s): %w", key, err)
+	}
+	return nil
+}
+
+func addMute(db *store.DB, mu Mute, now time.Time) error {
+	mutesMu.Lock()
+	defer mutesMu.Unlock()
+
+	m := readMutes(db, now)
+	if m == nil {
+		m = map[string]Mute{}
+	}
+	m[mu.Key] = mu
+	if err := db.Put(mutesKey, m); err != nil {
+		return fmt.Errorf("addMute(%s): %w", mu.Key, err)
+	}
+	return nil
+}
+
+func findMute(db *store.DB, key string, now time.Time) (Mute, bool) {
+	mutesMu.Lock()
+	defer mutesMu.Unlock()
+
+	mu, ok := readMutes(db, now)[key]
+	return mu, ok
+}
+
+// spamKey returns the key used to track the player username
+func spamKey(state appstate.AppState, username string) string {
+	if id := state.Presence.PlayerID(username); id != 0 {
+		return id.String()
+	}
+	return "user:" + username
+}
+
+type spamMsg struct {
+	key  string
+	norm string
+	ts   time.Time
+}
+
+// spamDetector tracks recent chat messages, per player and globally
+//
+// Messages that are duplicates, floods, too long or mostly caps are ignored,
+// and repeat offenders are muted.
+type spamDetector struct {
+	mu      sync.Mutex
+	recent  []spamMsg
+	strikes map[string][]time.Time
+	now     func() time.Time
+}
+
+func newSpamDetector() *spamDetector {
+	return &spamDetector{
+		strikes: map[string][]time.Time{},
+		now:     time.Now,
+	}
+}
+
+// normSpam reduces msg to lower-case letters and digits, with repeated characters collapsed
+func normSpam(msg string) string {
+	s := strings.Builder{}
+	var prev rune
+	for _, r := range strings.ToLower(msg) {
+		if (unicode.IsLetter(r) || unicode.IsDigit(r)) && r != prev {
+			s.WriteRune(r)
+			prev = r
+		}
+	}
+	return s.String()
+}
+
+// similarity returns how similar a and b are, from 0 to 1, based on their edit distance
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	p, q := []rune(a), []rune(b)
+	p, q = p[:min(len(p), maxSimilarityLen)], q[:min(len(q), maxSimilarityLen)]
+	n := max(len(p), len(q))
+	if n == 0 {
+		return 1
+	}
+
+	row := make([]int, len(q)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(p); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(q); j++ {
+			cost := 1
+			if p[i-1] == q[j-1] {
+				cost = 0
+			}
+			diag, row[j] = row[j], min(row[j]+1, row[j-1]+1, diag+cost)
+		}
+	}
+	return 1 - float64(row[len(q)])/float64(n)
+}
+
+func capsRatio(msg string) (ratio float64, letters int) {
+	upper := 0
+	for _, r := range msg {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters == 0 {
+		return 0, 0
+	}
+	return float64(upper) / float64(letters), letters
+}
+
+// violation returns the reason msg is spam, and whether it counts as a strike
+func (sd *spamDetector) violation(cfg config.SpamConfig, key, msg, norm string, now time.Time) (reason string, strike bool) {
+	if n := cfg.MaxLength; n > 0 && len(msg) > n {
+		return "too long", true
+	}
+
+	// short messages like GG or LOL are fine
+	if r, n := capsRatio(msg); cfg.MaxCaps > 0 && n >= 8 && r > cfg.MaxCaps {
+		return "too many caps", true
+	}
+
+	flood, globalFlood := 0, 0
+	for _, m := range sd.recent {
+		if now.Sub(m.ts) < cfg.FloodWindow.D {
+			globalFlood++
+			if m.key == key {
+				flood++
+			}
+		}
+	}
+	if n := cfg.FloodMessages; n > 0 && flood >= n {
+		return "flood", true
+	}
+	if n := cfg.GlobalFloodMessages; n > 0 && globalFlood >= n {
+		return "global flood", false
+	}
+
+	if norm == "" || cfg.Similarity <= 0 {
+		return "", false
+	}
+	for i := len(sd.recent) - 1; i >= 0; i-- {
+		m := sd.recent[i]
+		if now.Sub(m.ts) >= cfg.DuplicateWindow.D {
+			break
+		}
+		if similarity(norm, m.norm) < cfg.Similarity {
+			continue
+		}
+		if m.key == key {
+			return "duplicate", true
+		}
+		// players often say the same thing, e.g. gg, so it's skipped without a strike
+		return "duplicate of another player", false
+	}
+	return "", false
+}
+
+// check records the message msg from the player username,
+// and returns the reason it should be ignored, if it's spam
+func (sd *spamDetector) check(db *store.DB, cfg config.SpamConfig, key, username, msg string) (reason string) {
+	if !cfg.IsEnabled() {
+		return ""
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	now := sd.now()
+	if mu, ok := findMute(db, key, now); ok {
+		return fmt.Sprintf("muted until %s: %s", mu.Until.Format(time.Kitchen), mu.Reason)
+	}
+
+	i := 0
+	for i < len(sd.recent) && now.Sub(sd.recent[i].ts) >= cfg.Window.D {
+		i++
+	}
+	sd.recent = sd.recent[i:]
+
+	norm := normSpam(msg)
+	reason, strike := sd.violation(cfg, key, msg, norm, now)
+	sd.recent = append(sd.recent, spamMsg{key: key, norm: norm, ts: now})
+	if !strike {
+		return reason
+	}
+
+	strikes := sd.strikes[key][:0]
+	for _, ts := range sd.strikes[key] {
+		if now.Sub(ts) < cfg.StrikeWindow.D {
+			strikes = append(strikes, ts)
+		}
+	}
+	strikes = append(strikes, now)
+	sd.strikes[key] = strikes
+	if n := cfg.Strikes; n <= 0 || len(strikes) < n {
+		return reason
+	}
+
+	delete(sd.strikes, key)
+	mu := Mute{
+		Key:      key,
+		Username: username,
+		Reason:   reason,
+		Until:    now.Add(cfg.MuteDuration.D),
+	}
+	if err := addMute(db, mu, now); err != nil {
+		return reason + ": " + err.Error()
+	}
+	return "muted: " + reason
+}
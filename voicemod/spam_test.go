@@ -0,0 +1,102 @@
+package voicemod
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/store"
+)
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		A, B string
+		Min  float64
+		Max  float64
+	}{
+		{"hello", "hello", 1, 1},
+		{normSpam("FREE SKINS at x.y!!!"), normSpam("free skins at x.y"), 1, 1},
+		{"freeskinsatxy", "freeskinsatxyz", 0.9, 1},
+		{"goodgame", "welplayed", 0, 0.5},
+	}
+	for _, c := range cases {
+		if s := similarity(c.A, c.B); s < c.Min || s > c.Max {
+			t.Fatalf("similarity(`%s`, `%s`): Expected %v-%v; Got %v", c.A, c.B, c.Min, c.Max, s)
+		}
+	}
+}
+
+func TestSpamDetector(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	sd := newSpamDetector()
+	sd.now = func() time.Time { return now }
+	cfg := config.DefaultSpamConfig
+
+	check := func(key, msg, want string) {
+		t.Helper()
+		now = now.Add(3 * time.Second)
+		if r := sd.check(db, cfg, key, key, msg); !strings.HasPrefix(r, want) || (want == "" && r != "") {
+			t.Fatalf("check(%s, `%s`): Expected `%s`; Got `%s`", key, msg, want, r)
+		}
+	}
+
+	check("a", "gg", "")
+	check("a", "GG!!", "duplicate")
+	check("b", "gg", "duplicate of another player")
+	if n := len(sd.strikes["b"]); n != 0 {
+		t.Fatalf("Expected no strike for a duplicate of another player; Got %d", n)
+	}
+	check("b", "gg", "duplicate")
+	check("b", "THIS IS ALL CAPS", "too many caps")
+	check("b", strings.Repeat("x ", cfg.MaxLength), "muted: too long")
+	check("b", "hello", "muted until")
+
+	if l := Mutes(db); len(l) != 1 || l[0].Key != "b" {
+		t.Fatalf("Expected b to be muted; Got %v", l)
+	}
+	if err := Unmute(db, "b"); err != nil {
+		t.Fatal(err)
+	}
+	check("b", "back again", "")
+
+	// check advances the clock by 3s, so winding it back sends the messages at the same instant
+	now = now.Add(time.Hour)
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		check("c", msg, "")
+		now = now.Add(-3 * time.Second)
+	}
+	check("c", "five", "flood")
+}
+
+func TestIgnoreChatFlood(t *testing.T) {
+	db, err := store.OpenDB(t.TempDir(), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	app := &storeApp{fakeApp: newFakeApp(t, "alice"), db: db}
+	app.state.Config = config.DefaultConfig
+	app.state.IncludeUsernames = map[string]bool{"*": true}
+	vm := &voiceMod{spam: newSpamDetector(), app: app}
+
+	// most of the burst is rate limited, but it's still a flood
+	var reasons []string
+	for i := 0; i < 20; i++ {
+		reasons = append(reasons, vm.ignoreChat(app.state, "bob", fmt.Sprintf("message number %d", i)))
+	}
+	if !strings.HasPrefix(reasons[len(reasons)-1], "muted until") {
+		t.Fatalf("Expected the flooding player to be muted; Got %q", reasons)
+	}
+	if l := Mutes(db); len(l) != 1 || l[0].Username != "bob" {
+		t.Fatalf("Expected a mute for bob; Got %+v", l)
+	}
+}
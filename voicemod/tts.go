@@ -0,0 +1,226 @@
+package voicemod
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amitybell/srcvox/audio"
+)
+
+const (
+	ttsWorkers   = 2
+	ttsQueueSize = 32
+	ttsTimeout   = 30 * time.Second
+)
+
+var (
+	ErrTTSQueueFull = errors.New("TTS queue is full")
+
+	ttsStats struct {
+		queued    atomic.Int64
+		running   atomic.Int64
+		completed atomic.Uint64
+		failed    atomic.Uint64
+		timedOut  atomic.Uint64
+		cancelled atomic.Uint64
+		dropped   atomic.Uint64
+		latency   atomic.Int64
+		maxLat    atomic.Int64
+	}
+)
+
+// TTSMetrics is a snapshot of the TTS worker pool's metrics
+type TTSMetrics struct {
+	// Queued is the number of jobs that haven't been delivered yet, including running jobs
+	Queued int64 `json:"queued"`
+	// Running is the number of jobs being synthesized
+	Running   int64  `json:"running"`
+	Completed uint64 `json:"completed"`
+	Failed    uint64 `json:"failed"`
+	TimedOut  uint64 `json:"timedOut"`
+	Cancelled uint64 `json:"cancelled"`
+	// Dropped is the number of jobs that were dropped because the queue was full
+	Dropped uint64 `json:"dropped"`
+	// Latency is the moving average of the synthesis latency
+	Latency time.Duration `json:"latency"`
+	// MaxLatency is the largest synthesis latency
+	MaxLatency time.Duration `json:"maxLatency"`
+}
+
+// Metrics returns the metrics of the TTS worker pool
+func Metrics() TTSMetrics {
+	s := &ttsStats
+	return TTSMetrics{
+		Queued:     s.queued.Load(),
+		Running:    s.running.Load(),
+		Completed:  s.completed.Load(),
+		Failed:     s.failed.Load(),
+		TimedOut:   s.timedOut.Load(),
+		Cancelled:  s.cancelled.Load(),
+		Dropped:    s.dropped.Load(),
+		Latency:    time.Duration(s.latency.Load()),
+		MaxLatency: time.Duration(s.maxLat.Load()),
+	}
+}
+
+func recordLatency(d time.Duration) {
+	s := &ttsStats
+	for {
+		p := s.latency.Load()
+		// exponential moving average, weighted 1/8 to the new value
+		n := int64(d)
+		if p != 0 {
+			n = p + (int64(d)-p)/8
+		}
+		if s.latency.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	for {
+		p := s.maxLat.Load()
+		if int64(d) <= p || s.maxLat.CompareAndSwap(p, int64(d)) {
+			break
+		}
+	}
+}
+
+// ttsJob is a message waiting to be synthesized
+type ttsJob struct {
+	ctx    context.Context
+	synth  func(ctx context.Context) (*audio.Audio, error)
+	result chan *audio.Audio
+}
+
+// ttsPool synthesizes messages on a bounded number of workers,
+// so slow synthesis doesn't block reading the console
+//
+// Results are delivered in the order the jobs were submitted.
+type ttsPool struct {
+	jobs    chan *ttsJob
+	order   chan *ttsJob
+	timeout time.Duration
+	deliver func(*audio.Audio)
+	logf    func(format string, a ...any)
+
+	mu     sync.Mutex
+	ctx    context.Context
+	gen    context.Context
+	cancel context.CancelFunc
+}
+
+// newTTSPool starts a pool that delivers results to deliver, until ctx is cancelled
+func newTTSPool(ctx context.Context, workers int, deliver func(*audio.Audio), logf func(format string, a ...any)) *ttsPool {
+	p := &ttsPool{
+		jobs:    make(chan *ttsJob, ttsQueueSize),
+		order:   make(chan *ttsJob, ttsQueueSize),
+		timeout: ttsTimeout,
+		deliver: deliver,
+		logf:    logf,
+		ctx:     ctx,
+	}
+	p.gen, p.cancel = context.WithCancel(ctx)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	go p.deliverLoop()
+	return p
+}
+
+// submit queues synth to be run by a worker
+//
+// If the queue is full, the job is dropped.
+func (p *ttsPool) submit(synth func(ctx context.Context) (*audio.Audio, error)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	j := &ttsJob{ctx: p.gen, synth: synth, result: make(chan *audio.Audio, 1)}
+	// order is only written while mu is held, so checking its length first is enough to not block
+	if len(p.order) == cap(p.order) {
+		ttsStats.dropped.Add(1)
+		return ErrTTSQueueFull
+	}
+	ttsStats.queued.Add(1)
+	p.order <- j
+	p.jobs <- j
+	return nil
+}
+
+// cancelAll cancels the jobs that are queued or running
+func (p *ttsPool) cancelAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancel()
+	p.gen, p.cancel = context.WithCancel(p.ctx)
+}
+
+func (p *ttsPool) work() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case j := <-p.jobs:
+			j.result <- p.run(j)
+		}
+	}
+}
+
+func (p *ttsPool) run(j *ttsJob) *audio.Audio {
+	if j.ctx.Err() != nil {
+		ttsStats.cancelled.Add(1)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(j.ctx, p.timeout)
+	defer cancel()
+
+	ttsStats.running.Add(1)
+	defer ttsStats.running.Add(-1)
+
+	start := time.Now()
+	au, err := j.synth(ctx)
+	recordLatency(time.Since(start))
+	switch {
+	case err == nil:
+		ttsStats.completed.Add(1)
+		return au
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		ttsStats.timedOut.Add(1)
+		p.logf("ttsPool: Timed out after %s: %s\n", p.timeout, err)
+	case ctx.Err() != nil:
+		ttsStats.cancelled.Add(1)
+	default:
+		ttsStats.failed.Add(1)
+		p.logf("ttsPool: %s\n", err)
+	}
+	return nil
+}
+
+func (p *ttsPool) deliverLoop() {
+	// jobs that will never be delivered are no longer queued
+	defer func() { ttsStats.queued.Add(-int64(len(p.order))) }()
+
+	for {
+		var j *ttsJob
+		select {
+		case <-p.ctx.Done():
+			return
+		case j = <-p.order:
+		}
+
+		select {
+		case au := <-j.result:
+			ttsStats.queued.Add(-1)
+			// the job might have been cancelled after it completed
+			if au != nil && j.ctx.Err() == nil {
+				p.deliver(au)
+			}
+		case <-p.ctx.Done():
+			ttsStats.queued.Add(-1)
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package voicemod
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/audio"
+	"github.com/gopxl/beep"
+)
+
+func testAudio(name string) *audio.Audio {
+	return &audio.Audio{Name: name, Format: beep.Format{SampleRate: 22050, NumChannels: 1, Precision: 2}}
+}
+
+// sleepJob returns a job that takes d to synthesize name, unless it's cancelled
+func sleepJob(name string, d time.Duration) func(ctx context.Context) (*audio.Audio, error) {
+	return func(ctx context.Context) (*audio.Audio, error) {
+		select {
+		case <-time.After(d):
+			return testAudio(name), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func recvNames(t *testing.T, q chan *audio.Audio, n int) []string {
+	t.Helper()
+	var names []string
+	for len(names) < n {
+		select {
+		case au := <-q:
+			names = append(names, au.Name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected %d results; Got %q", n, names)
+		}
+	}
+	return names
+}
+
+func TestTTSPoolOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := make(chan *audio.Audio, 8)
+	p := newTTSPool(ctx, 3, func(au *audio.Audio) { q <- au }, t.Logf)
+	p.submit(sleepJob("slow", 100*time.Millisecond))
+	p.submit(sleepJob("fast", 0))
+	p.submit(func(context.Context) (*audio.Audio, error) { return nil, errors.New("fail") })
+	p.submit(sleepJob("last", 10*time.Millisecond))
+
+	names := recvNames(t, q, 3)
+	if names[0] != "slow" || names[1] != "fast" || names[2] != "last" {
+		t.Fatalf("Expected results in order; Got %q", names)
+	}
+}
+
+func TestTTSPoolTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := make(chan *audio.Audio, 8)
+	p := newTTSPool(ctx, 1, func(au *audio.Audio) { q <- au }, t.Logf)
+	p.timeout = 20 * time.Millisecond
+	timedOut := Metrics().TimedOut
+	p.submit(sleepJob("stuck", time.Hour))
+	p.submit(sleepJob("next", 0))
+
+	if names := recvNames(t, q, 1); names[0] != "next" {
+		t.Fatalf("Expected the stuck job to time out; Got %q", names)
+	}
+	if n := Metrics().TimedOut; n != timedOut+1 {
+		t.Fatalf("Expected TimedOut to be %d; Got %d", timedOut+1, n)
+	}
+}
+
+func TestTTSPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := make(chan *audio.Audio, 8)
+	p := newTTSPool(ctx, 1, func(au *audio.Audio) { q <- au }, t.Logf)
+	p.submit(sleepJob("running", time.Hour))
+	p.submit(sleepJob("queued", 0))
+	p.cancelAll()
+	p.submit(sleepJob("after", 0))
+
+	if names := recvNames(t, q, 1); names[0] != "after" {
+		t.Fatalf("Expected the cancelled jobs to be dropped; Got %q", names)
+	}
+}
+
+func TestTTSPoolFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := newTTSPool(ctx, 1, func(*audio.Audio) {}, t.Logf)
+	var err error
+	for i := 0; i <= ttsQueueSize+1 && err == nil; i++ {
+		err = p.submit(sleepJob("stuck", time.Hour))
+	}
+	if !errors.Is(err, ErrTTSQueueFull) {
+		t.Fatalf("Expected %v; Got %v", ErrTTSQueueFull, err)
+	}
+}
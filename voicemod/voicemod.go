@@ -9,14 +9,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unicode"
 
 	"github.com/amitybell/memio"
-	"github.com/amitybell/piper"
 	"github.com/amitybell/srcvox/appstate"
 	"github.com/amitybell/srcvox/audio"
 	"github.com/amitybell/srcvox/data"
@@ -26,6 +25,7 @@ import (
 	"github.com/amitybell/srcvox/sound"
 	"github.com/amitybell/srcvox/steam"
 	"github.com/amitybell/srcvox/store"
+	"github.com/amitybell/srcvox/translate"
 	"github.com/gopxl/beep"
 	"golang.org/x/time/rate"
 )
@@ -48,6 +48,8 @@ var (
 	ConnectPat      = regexp.MustCompile(`(?i)^\s*(.*)\s*(connected|disconnected|Not connected to server)\s*$`)
 	StatusPat       = regexp.MustCompile(`^#\s+\d+(?:\s+\d+)?\s+"([^"]+)".+(STEAM_\d+:\d+:\d+|BOT)`)
 	StatusServerPat = regexp.MustCompile(`^\s*Connected to (\S+:\d+)\s*$`)
+	NameCmdPat      = regexp.MustCompile(`(?i)^!name(?:\s+(.*?))?\s*$`)
+	VoiceCmdPat     = regexp.MustCompile(`(?i)^!voice(?:\s+(.*?))?\s*$`)
 
 	StatusTableBegin = `# userid name uniqueid connected ping loss state rate`
 	StatusTableEnd   = `#end`
@@ -63,7 +65,11 @@ type App interface {
 	State() appstate.AppState
 	Logs() *logs.Logger
 	Limiter(name string) *rate.Limiter
-	TTS(key string) *piper.TTS
+	TTS(username string) sound.TTS
+	TranslateVars(username string) translate.Vars
+	TextFilter() *translate.Filter
+	SetSpokenName(username, name string) error
+	SetVoice(username, voice string) error
 	VoiceModStopped(err error)
 	VoiceModGame(ts time.Time, game *steam.GameInfo, gameDir string)
 	VoiceModPresence(ts time.Time, server string, hums, bots data.SliceSet[steam.Profile])
@@ -88,6 +94,10 @@ type voiceMod struct {
 
 	statusServer atomic.Pointer[string]
 
+	binds *keyBinds
+	spam  *spamDetector
+	tts   *ttsPool
+
 	app App
 }
 
@@ -210,10 +220,39 @@ func (vm *voiceMod) play(au *audio.Audio) (err error) {
 }
 
 func (vm *voiceMod) readLineCvar(name, val string) {
+	if vm.binds.readBind(name, val) {
+		return
+	}
 	switch name {
 	}
 }
 
+func (vm *voiceMod) readLineUnbound(key string) {
+	vm.binds.readBind(key, "")
+}
+
+func (vm *voiceMod) readLineBind(text string) {
+	state := vm.app.State()
+	vm.say(state, state.Presence.Username, text)
+}
+
+// say queues text, said by the player name, to be synthesized and played
+func (vm *voiceMod) say(state appstate.AppState, name, text string) {
+	tts := vm.app.TTS(name)
+	filter := vm.app.TextFilter()
+	vars := vm.app.TranslateVars(name)
+	err := vm.tts.submit(func(ctx context.Context) (*audio.Audio, error) {
+		au, err := sound.SoundOrTTS(ctx, tts, state.Config, filter, vars, text)
+		if err != nil {
+			return nil, fmt.Errorf("username=`%s`, text=`%s`: %w", name, text, err)
+		}
+		return au, nil
+	})
+	if err != nil {
+		vm.app.Logs().Printf("voiceMod.say: username=`%s`, text=`%s`: %s\n", name, text, err)
+	}
+}
+
 func (vm *voiceMod) readLineGamePath(steamDir, gameNm string) {
 	ts := time.Now()
 	var game *steam.GameInfo
@@ -302,9 +341,12 @@ func (vm *voiceMod) readLineConnect(name, status string) {
 	switch strings.ToLower(status) {
 	case "connected":
 		vm.execStatus()
+		vm.binds.apply(vm.app.State().Binds)
 	case "disconnected":
 		vm.execStatus()
+		vm.binds.restore()
 	case "not connected to server":
+		vm.binds.restore()
 		vm.app.VoiceModServerDisconnected()
 	}
 }
@@ -318,7 +360,7 @@ func (vm *voiceMod) hostInGame(state appstate.AppState) (string, bool) {
 	return "", false
 }
 
-func (vm *voiceMod) ignoreChat(state appstate.AppState, name string) (reason string) {
+func (vm *voiceMod) ignoreChat(state appstate.AppState, name, msg string) (reason string) {
 	pr := state.Presence
 
 	if name == pr.Username {
@@ -337,6 +379,11 @@ func (vm *voiceMod) ignoreChat(state appstate.AppState, name string) (reason str
 		return "not included"
 	}
 
+	// rate limited messages are still checked, so floods get strikes
+	if r := vm.spam.check(vm.app.Store(), state.Spam, spamKey(state, name), name, msg); r != "" {
+		return r
+	}
+
 	if !vm.app.Limiter(name).Allow() {
 		return "rate limited"
 	}
@@ -347,17 +394,47 @@ func (vm *voiceMod) ignoreChat(state appstate.AppState, name string) (reason str
 func (vm *voiceMod) readLineChat(name, msg string) {
 	state := vm.app.State()
 
-	if r := vm.ignoreChat(state, name); r != "" {
+	if r := vm.ignoreChat(state, name, msg); r != "" {
 		vm.app.Logs().Printf("readLineChat: ignored: `%s: %s`: %s\n", name, msg, r)
 		return
 	}
 
-	au, err := sound.SoundOrTTS(vm.app.TTS(name), state.Config, name, msg)
-	if err != nil {
-		vm.app.Logs().Printf("voiceMod.readLine: username=`%s`, message=`%s`: %s\n", name, msg, err)
+	if m := NameCmdPat.FindStringSubmatch(msg); m != nil {
+		vm.readNameCmd(state, name, m[1])
 		return
 	}
 
+	if m := VoiceCmdPat.FindStringSubmatch(msg); m != nil {
+		vm.readVoiceCmd(state, name, m[1])
+		return
+	}
+
+	vm.say(state, name, msg)
+}
+
+// readNameCmd handles `!name <spoken name>`, and says the new name back to the player
+func (vm *voiceMod) readNameCmd(state appstate.AppState, name, spoken string) {
+	if err := vm.app.SetSpokenName(name, spoken); err != nil {
+		vm.app.Logs().Printf("voiceMod.readNameCmd: username=`%s`, name=`%s`: %s\n", name, spoken, err)
+		return
+	}
+	vm.sayName(state, name)
+}
+
+// readVoiceCmd handles `!voice <voice name>`, and says the player's name in the new voice
+func (vm *voiceMod) readVoiceCmd(state appstate.AppState, name, voice string) {
+	if err := vm.app.SetVoice(name, voice); err != nil {
+		vm.app.Logs().Printf("voiceMod.readVoiceCmd: username=`%s`, voice=`%s`: %s\n", name, voice, err)
+		return
+	}
+	vm.sayName(state, name)
+}
+
+func (vm *voiceMod) sayName(state appstate.AppState, name string) {
+	vm.say(state, name, "$name")
+}
+
+func (vm *voiceMod) enqueue(au *audio.Audio) {
 	select {
 	case vm.Q <- au:
 	case <-vm.Q:
@@ -379,6 +456,7 @@ func (vm *voiceMod) readLine(line string) error {
 	}
 
 	if strings.ReplaceAll(line, " ", "") == StopWord {
+		vm.tts.cancelAll()
 		select {
 		case vm.stop <- struct{}{}:
 		default:
@@ -386,6 +464,16 @@ func (vm *voiceMod) readLine(line string) error {
 		return nil
 	}
 
+	if ln := BindPat.FindStringSubmatch(line); len(ln) == 2 {
+		vm.readLineBind(ln[1])
+		return nil
+	}
+
+	if ln := BindUnboundPat.FindStringSubmatch(line); len(ln) == 2 {
+		vm.readLineUnbound(ln[1])
+		return nil
+	}
+
 	if line == StatusTableBegin {
 		vm.readStatusTable(vm.Conn)
 		return nil
@@ -443,6 +531,7 @@ func (vm *voiceMod) execInit() {
 	if err := vm.execStatus(); err != nil {
 		vm.app.Logs().Println(err)
 	}
+	vm.binds.apply(vm.app.State().Binds)
 }
 
 func (vm *voiceMod) commandLoop(ctx context.Context) {
@@ -452,10 +541,14 @@ func (vm *voiceMod) commandLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-time.After(10 * time.Second):
-			if vm.gameDir(vm.app.State()) == "" {
+			state := vm.app.State()
+			if vm.gameDir(state) == "" {
 				vm.execInit()
 			} else {
 				vm.execStatus()
+				if state.Presence.Server != "" {
+					vm.binds.apply(state.Binds)
+				}
 			}
 		}
 	}
@@ -513,9 +606,22 @@ func runVM(ctx context.Context, app App) (retErr error) {
 		Conn: c,
 		app:  app,
 	}
+	vm.binds = newKeyBinds(vm)
+	vm.spam = newSpamDetector()
+	// the pool stops when the connection drops
+	vm.tts = newTTSPool(ctx, ttsWorkers, vm.enqueue, app.Logs().Printf)
+
+	// the connection is only closed after the user's binds are restored
+	closeConn := sync.OnceValue(c.Close)
+	go func() {
+		<-ctx.Done()
+		vm.binds.restore()
+		closeConn()
+	}()
 
 	loopErr := vm.Loop(ctx)
-	closeErr := c.Close()
+	vm.binds.restore()
+	closeErr := closeConn()
 	return errors.Join(loopErr, closeErr)
 }
 
@@ -536,11 +642,25 @@ func Run(ctx context.Context, app App) error {
 	}
 }
 
+// quote quotes s for the console if it contains spaces or special characters
+//
+// The console has no escape sequences, so `"` is replaced with `'` and control characters with spaces.
 func quote(s string) string {
-	for _, r := range s {
-		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
-			return strconv.QuoteToASCII(s)
+	special := func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r) || r == '"' || r == ';'
+	}
+	if !strings.ContainsFunc(s, special) {
+		return s
+	}
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '"':
+			return '\''
+		case unicode.IsControl(r):
+			return ' '
+		default:
+			return r
 		}
-	}
-	return s
+	}, s)
+	return `"` + s + `"`
 }
@@ -0,0 +1,98 @@
+package voicemod
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amitybell/srcvox/appstate"
+	"github.com/amitybell/srcvox/audio"
+	"github.com/amitybell/srcvox/data"
+	"github.com/amitybell/srcvox/logs"
+	"github.com/amitybell/srcvox/sound"
+	"github.com/amitybell/srcvox/steam"
+	"github.com/amitybell/srcvox/store"
+	"github.com/amitybell/srcvox/translate"
+	"golang.org/x/time/rate"
+)
+
+// fakeApp is an App that speaks with a sound.FakeSynthesizer
+type fakeApp struct {
+	state  appstate.AppState
+	logs   *logs.Logger
+	synth  *sound.FakeSynthesizer
+	voices map[string]string
+}
+
+func newFakeApp(t *testing.T, username string) *fakeApp {
+	fa := &fakeApp{
+		logs:   logs.NewLogger(filepath.Join(t.TempDir(), "logs.json"), slog.LevelError),
+		synth:  &sound.FakeSynthesizer{},
+		voices: map[string]string{},
+	}
+	fa.state.Presence.Username = username
+	return fa
+}
+
+func (fa *fakeApp) Store() *store.DB                   { return nil }
+func (fa *fakeApp) State() appstate.AppState           { return fa.state }
+func (fa *fakeApp) Logs() *logs.Logger                 { return fa.logs }
+func (fa *fakeApp) Limiter(string) *rate.Limiter       { return rate.NewLimiter(rate.Inf, 1) }
+func (fa *fakeApp) TextFilter() *translate.Filter      { return nil }
+func (fa *fakeApp) SetSpokenName(string, string) error { return nil }
+func (fa *fakeApp) VoiceModStopped(error)              {}
+func (fa *fakeApp) VoiceModServerDisconnected()        {}
+func (fa *fakeApp) VoiceModNetcon() (Conn, error)      { return nil, nil }
+func (fa *fakeApp) DedicatedGameDir() string           { return "" }
+
+func (fa *fakeApp) VoiceModGame(time.Time, *steam.GameInfo, string) {}
+
+func (fa *fakeApp) VoiceModPresence(time.Time, string, data.SliceSet[steam.Profile], data.SliceSet[steam.Profile]) {
+}
+
+func (fa *fakeApp) TTS(username string) sound.TTS {
+	return sound.TTS{Voice: sound.NewVoice("fake:"+fa.voices[username], fa.synth)}
+}
+
+func (fa *fakeApp) TranslateVars(username string) translate.Vars {
+	return translate.Vars{"name": username}
+}
+
+func (fa *fakeApp) SetVoice(username, voice string) error {
+	fa.voices[username] = voice
+	return nil
+}
+
+func TestReadLineChat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fa := newFakeApp(t, "alice")
+	vm := &voiceMod{Q: make(chan *audio.Audio, 4), spam: newSpamDetector(), app: fa}
+	vm.tts = newTTSPool(ctx, ttsWorkers, vm.enqueue, t.Logf)
+
+	if err := vm.readLine("alice : :> testing the fake voice"); err != nil {
+		t.Fatalf("readLine: %s", err)
+	}
+	if err := vm.readLine("alice : :> !voice robot"); err != nil {
+		t.Fatalf("readLine: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-vm.Q:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected 2 queued messages; Got %d", i)
+		}
+	}
+	if v := fa.voices["alice"]; v != "robot" {
+		t.Fatalf("Expected the voice to be set to robot; Got `%s`", v)
+	}
+	texts, _ := fa.synth.Calls()
+	if exp := []string{"testing the fake voice", "alice"}; !slices.Equal(texts, exp) {
+		t.Fatalf("Expected synthesized texts %q; Got %q", exp, texts)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	asset "github.com/amitybell/piper-asset"
+	alan "github.com/amitybell/piper-voice-alan"
+	jenny "github.com/amitybell/piper-voice-jenny"
+	"github.com/amitybell/srcvox/config"
+	"github.com/amitybell/srcvox/sound"
+	"github.com/amitybell/srcvox/store"
+)
+
+func (app *App) initPiper(firstVoice string) error {
+	assets := []asset.Asset{jenny.Asset, alan.Asset}
+	if firstVoice == "alan" {
+		assets = []asset.Asset{alan.Asset, jenny.Asset}
+	}
+
+	piperDir, err := sound.PiperDir()
+	if err != nil {
+		return err
+	}
+	app.piperDir = piperDir
+
+	for _, a := range assets {
+		l, err := sound.EmbeddedVoice(piperDir, a)
+		if err != nil {
+			return err
+		}
+		app.embeddedVoices = append(app.embeddedVoices, l...)
+	}
+
+	os.MkdirAll(app.Paths.VoicesDir, 0o755)
+	go app.reloadVoices()
+	app.loadVoices()
+	return nil
+}
+
+// loadVoices (re-)loads the models in Paths.VoicesDir, and the voices in Config.TTSVoices
+//
+// Models that fail to load are reported as non-fatal errors, and the other voices are still loaded.
+func (app *App) loadVoices() {
+	disk, errs := sound.LoadVoices(app.Paths.VoicesDir, app.piperDir)
+	cfgVoices, cfgErrs := sound.ConfigVoices(app.State().TTSVoices, app.piperDir)
+	disk = append(disk, cfgVoices...)
+	errs = append(errs, cfgErrs...)
+	for _, err := range errs {
+		Logs.Println("loadVoices:", err)
+		app.Error(false, fmt.Errorf("Cannot load voice: %w", err))
+	}
+
+	voices := append([]*sound.Voice(nil), app.embeddedVoices...)
+	seen := map[string]bool{}
+	for _, v := range voices {
+		seen[strings.ToLower(v.Name)] = true
+	}
+	for _, v := range disk {
+		if seen[strings.ToLower(v.Name)] {
+			app.Error(false, fmt.Errorf("Cannot load voice `%s`: A voice with the same name already exists", v.Name))
+			continue
+		}
+		seen[strings.ToLower(v.Name)] = true
+		voices = append(voices, v)
+	}
+
+	app.mu.Lock()
+	app.voices = voices
+	app.mu.Unlock()
+	Logs.Printf("loadVoices: %d voices\n", len(voices))
+}
+
+func (app *App) reloadVoices() {
+	for range app.tmr.reloadVoices.C {
+		app.loadVoices()
+	}
+}
+
+func isVoiceFile(fn string) bool {
+	return strings.HasSuffix(fn, sound.ModelExt) || strings.HasSuffix(fn, sound.ModelConfigExt)
+}
+
+func (app *App) voiceList() []*sound.Voice {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	return app.voices
+}
+
+// Voices returns the names of the available voices
+func (app *App) Voices() []string {
+	voices := app.voiceList()
+	l := make([]string, len(voices))
+	for i, v := range voices {
+		l[i] = v.Name
+	}
+	return l
+}
+
+func (app *App) findVoice(name string) (*sound.Voice, bool) {
+	for _, v := range app.voiceList() {
+		if strings.EqualFold(v.Name, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// VoiceName returns the name of the voice used for the player username
+//
+// In order of precedence, it's the entry in Config.Voices, the voice the player chose with !voice,
+// Config.FirstVoice for the user, or a voice derived from the player's steam ID.
+func (app *App) VoiceName(username string) string {
+	voices := app.voiceList()
+	if len(voices) == 0 {
+		return ""
+	}
+
+	state := app.State()
+	id := state.Presence.PlayerID(username)
+	if s, ok := playerEntry(state.Voices, id, username); ok {
+		if v, ok := app.findVoice(s); ok {
+			return v.Name
+		}
+	}
+	if s, _ := store.Get[string](app.DB, playerKey("/voices", id, username)); s != "" {
+		if v, ok := app.findVoice(s); ok {
+			return v.Name
+		}
+	}
+	if username == state.Presence.Username {
+		// initPiper puts Config.FirstVoice first
+		return voices[0].Name
+	}
+
+	// only the embedded voices are used, so adding a model doesn't change everyone's voice
+	n := uint32(id)
+	if id == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(username))
+		n = h.Sum32()
+	}
+	if len(app.embeddedVoices) != 0 {
+		voices = app.embeddedVoices
+	}
+	return voices[n%uint32(len(voices))].Name
+}
+
+// TTS returns the voice and synthesis params for the player username
+func (app *App) TTS(username string) sound.TTS {
+	v, _ := app.findVoice(app.VoiceName(username))
+	return sound.TTS{
+		Voice:  v,
+		Params: app.TTSParams(username, v),
+		FindVoice: func(name string) (*sound.Voice, config.TTSParams, bool) {
+			v, ok := app.findVoice(name)
+			if !ok {
+				return nil, config.TTSParams{}, false
+			}
+			return v, app.TTSParams(username, v), true
+		},
+	}
+}
+
+// TTSParams returns the synthesis params used for the player username speaking with voice
+//
+// Fields set in Config.PlayerParams override those in Config.VoiceParams,
+// where entries for the voice override those for its model.
+func (app *App) TTSParams(username string, voice *sound.Voice) config.TTSParams {
+	var p config.TTSParams
+	state := app.State()
+	if voice != nil {
+		p = p.Over(state.VoiceParams[voice.Model])
+		if voice.Name != voice.Model {
+			p = p.Over(state.VoiceParams[voice.Name])
+		}
+	}
+	if username == "" {
+		return p
+	}
+	if q, ok := playerEntry(state.PlayerParams, state.Presence.PlayerID(username), username); ok {
+		p = p.Over(q)
+	}
+	return p
+}
+
+// SetVoice sets the voice used for the player username
+//
+// If name is empty, the player's voice is reset.
+func (app *App) SetVoice(username, name string) error {
+	if name != "" {
+		v, ok := app.findVoice(name)
+		if !ok {
+			return fmt.Errorf("SetVoice(%s): Unknown voice `%s`; Available voices: %s", username, name, strings.Join(app.Voices(), ", "))
+		}
+		name = v.Name
+	}
+	id := app.State().Presence.PlayerID(username)
+	if err := store.Put(app.DB, playerKey("/voices", id, username), name); err != nil {
+		return fmt.Errorf("SetVoice(%s): %w", username, err)
+	}
+	return nil
+}
+
+// parseTTSParams parses the params in the query q, as encoded by ttsQuery
+func parseTTSParams(q url.Values) (config.TTSParams, error) {
+	p := config.TTSParams{Speaker: q.Get("speaker")}
+	for k, f := range map[string]*float64{"rate": &p.Rate, "variability": &p.Variability, "cadence": &p.Cadence} {
+		s := q.Get(k)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			return p, fmt.Errorf("parseTTSParams: Invalid %s `%s`", k, s)
+		}
+		*f = v
+	}
+	return p, nil
+}
+
+// ttsQuery returns the query used by serveSynthesize to preview text spoken by voice with params p
+func ttsQuery(text, voice string, p config.TTSParams) url.Values {
+	q := url.Values{"text": {text}}
+	set := func(k, v string) {
+		if v != "" && v != "0" {
+			q.Set(k, v)
+		}
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	set("voice", voice)
+	set("rate", f(p.Rate))
+	set("variability", f(p.Variability))
+	set("cadence", f(p.Cadence))
+	set("speaker", p.Speaker)
+	return q
+}